@@ -1,14 +1,39 @@
 package config
 
 type BotConfig struct {
-	OpenstreetmapDomain string  `env:"openstreetmapDomain"`
-	TelegramDomain      string  `env:"telegramDomain"`
-	BotToken            string  `env:"botToken"`
-	UserTicker          int     `env:"userTicker"`
-	AlertTicker         int     `env:"alertTicker"`
-	USGSUrl             string  `env:"usgsURL"`
-	Magnitude           float64 `env:"magnitude"`
-	MapURL              string  `env:"mapURL"`
+	OpenstreetmapDomain string   `env:"openstreetmapDomain"`
+	TelegramDomain      string   `env:"telegramDomain"`
+	BotToken            string   `env:"botToken"`
+	UserTicker          int      `env:"userTicker" envDefault:"14"`
+	AlertTicker         int      `env:"alertTicker" envDefault:"15"`
+	USGSUrl             string   `env:"usgsURL"`
+	Magnitude           float64  `env:"magnitude"`
+	MapURL              string   `env:"mapURL"`
+	Sources             []string `env:"sources" envDefault:"usgs"`
+	EMSCUrl             string   `env:"emscURL"`
+	FDSNUrl             string   `env:"fdsnURL"`
+	FDSNFormat          string   `env:"fdsnFormat" envDefault:"geojson"`
+	FDSNWindow          int      `env:"fdsnWindow" envDefault:"60"`
+	AssociationWindow   int      `env:"associationWindow" envDefault:"30"`
+	AssociationRadius   float64  `env:"associationRadius" envDefault:"100"`
+	RevisionThreshold   float64  `env:"revisionThreshold" envDefault:"0.3"`
+	TemplateDir         string   `env:"templateDir"`
+	ParseMode           string   `env:"parseMode" envDefault:"MarkdownV2"`
+	AlertMap            bool     `env:"alertMap" envDefault:"true"`
+	TsunamiRadius       float64  `env:"tsunamiRadius" envDefault:"1000"`
+	SequenceMagnitude   float64  `env:"sequenceMagnitude" envDefault:"5.5"`
+	AftershockSummary   float64  `env:"aftershockSummary"`
+	SummaryInterval     int      `env:"summaryInterval" envDefault:"60"`
+	SwarmCount          int      `env:"swarmCount" envDefault:"5"`
+	SwarmRadius         float64  `env:"swarmRadius" envDefault:"20"`
+	SwarmWindow         int      `env:"swarmWindow" envDefault:"24"`
+	SwarmListURL        string   `env:"swarmListURL" envDefault:"https://earthquake.usgs.gov/fdsnws/event/1/query"`
+	EventRetentionDays  int      `env:"eventRetentionDays" envDefault:"30"`
+	HealthAddr          string   `env:"healthAddr"`
+	HealthThreshold     int      `env:"healthThreshold" envDefault:"5"`
+	ShutdownTimeout     int      `env:"shutdownTimeout" envDefault:"15"`
+	LeaderLockKey       int64    `env:"leaderLockKey" envDefault:"7305523"`
+	LeaderInterval      int      `env:"leaderInterval" envDefault:"5"`
 }
 type DBConfig struct {
 	DatabaseName     string `env:"databaseName"`
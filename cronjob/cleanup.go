@@ -1,24 +1,36 @@
 package cronjob
 
 import (
+	"alerts/config"
+	"alerts/internal/leader"
 	"alerts/repository"
 	"log"
 
 	"github.com/robfig/cron/v3"
 )
 
-func ScheduleCleanupJob() {
+func ScheduleCleanupJob() *cron.Cron {
 	c := cron.New()
 	_, err := c.AddFunc("0 0 * * *", func() {
-		err := repository.ClearAllUpdatesForADay()
+		if !leader.IsLeader() {
+			return
+		}
+		err := repository.ClearAllUpdatesForADay(config.BotConf.SequenceMagnitude)
 		if err != nil {
 			log.Println("Error cleaning up data:", err)
 		} else {
 			log.Println("Successfully cleaned up the data")
 		}
+		if err = repository.ClearOldEvents(config.BotConf.EventRetentionDays); err != nil {
+			log.Println("Error cleaning up events:", err)
+		}
+		if err = repository.ClearOldSwarms(config.BotConf.EventRetentionDays); err != nil {
+			log.Println("Error cleaning up swarms:", err)
+		}
 	})
 	if err != nil {
 		log.Fatal("Failed to schedule job:", err)
 	}
 	c.Start()
+	return c
 }
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"alerts/internal/i18n"
+	"alerts/internal/telegram"
+	"alerts/model"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Telegram rejects inline buttons whose callback_data is longer than this.
+const maxCallbackDataLength = 64
+
+const callbackSeparator = ":"
+
+var ErrInvalidCallback = errors.New("invalid callback data")
+
+// Callback is parsed callback data of the form "<prefix>:<value>", e.g.
+// "cty:jp" or "mag:5.0". Prefixes without a value, like "unsub", are encoded
+// as just the prefix.
+type Callback struct {
+	Prefix string
+	Value  string
+}
+
+type CallbackHandler func(ctx context.Context, query *model.CallbackQuery, value string) error
+
+type callbackRoute struct {
+	validate func(value string) error
+	handle   CallbackHandler
+}
+
+// Dispatcher routes callback queries to the handler registered for their prefix.
+type Dispatcher struct {
+	routes map[string]*callbackRoute
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{routes: map[string]*callbackRoute{}}
+}
+
+// Register adds a handler for prefix. validate is run on the value before the
+// handler and on encoding; nil accepts only an empty value.
+func (d *Dispatcher) Register(prefix string, validate func(value string) error, handle CallbackHandler) {
+	if validate == nil {
+		validate = noValue
+	}
+	d.routes[prefix] = &callbackRoute{validate: validate, handle: handle}
+}
+
+// Encode builds the callback data for prefix and value, checking it would be
+// accepted by both Parse and Telegram.
+func (d *Dispatcher) Encode(prefix, value string) (string, error) {
+	route, ok := d.routes[prefix]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown prefix %q", ErrInvalidCallback, prefix)
+	}
+	if err := route.validate(value); err != nil {
+		return "", err
+	}
+	data := prefix
+	if value != "" {
+		data = prefix + callbackSeparator + value
+	}
+	if len(data) > maxCallbackDataLength {
+		return "", fmt.Errorf("%w: %q is longer than %d bytes", ErrInvalidCallback, data, maxCallbackDataLength)
+	}
+	return data, nil
+}
+
+// Parse splits and validates callback data.
+func (d *Dispatcher) Parse(data string) (*Callback, error) {
+	if data == "" || len(data) > maxCallbackDataLength {
+		return nil, fmt.Errorf("%w: bad length", ErrInvalidCallback)
+	}
+	prefix, value, _ := strings.Cut(data, callbackSeparator)
+	route, ok := d.routes[prefix]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown prefix %q", ErrInvalidCallback, prefix)
+	}
+	if err := route.validate(value); err != nil {
+		return nil, err
+	}
+	return &Callback{Prefix: prefix, Value: value}, nil
+}
+
+// Dispatch parses the query's data and runs the matching handler. Invalid data
+// is answered with a toast rather than returned, since retrying won't fix it.
+func (d *Dispatcher) Dispatch(ctx context.Context, query *model.CallbackQuery) error {
+	callback, err := d.Parse(query.Data)
+	if err != nil {
+		log.Println("rejecting callback query:", err)
+		answerCallback(ctx, query, i18n.T(queryLanguage(query), "callback.invalid"))
+		return nil
+	}
+	return d.routes[callback.Prefix].handle(ctx, query, callback.Value)
+}
+
+func noValue(value string) error {
+	if value != "" {
+		return fmt.Errorf("%w: unexpected value %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+// queryChatId is the chat a tapped button was shown in. Settings belong to
+// that chat, so in a group a tap changes the group's settings rather than
+// those of the member who tapped.
+func queryChatId(query *model.CallbackQuery) int64 {
+	if query.Message != nil && query.Message.Chat != nil {
+		return query.Message.Chat.Id
+	}
+	return query.From.Id
+}
+
+func answerCallback(ctx context.Context, query *model.CallbackQuery, text string) {
+	answer := &telegram.AnswerCallbackQueryParams{
+		CallbackQueryID: query.Id,
+		Text:            text,
+	}
+	if err := telegram.Bot.AnswerCallbackQuery(ctx, answer); err != nil {
+		log.Println("error answering the callback query:", err)
+	}
+}
+
+// callbackButton builds an inline button, logging and dropping data that
+// fails validation.
+func callbackButton(text, prefix, value string) (model.InlineKeyBoardButton, bool) {
+	data, err := callbacks.Encode(prefix, value)
+	if err != nil {
+		log.Println("error encoding callback data:", err)
+		return model.InlineKeyBoardButton{}, false
+	}
+	return model.InlineKeyBoardButton{Text: text, CallbackData: data}, true
+}
+
+type buttonSpec struct {
+	text   string
+	prefix string
+	value  string
+}
+
+// keyboardRow builds a row of buttons, skipping any that failed to encode.
+func keyboardRow(specs ...buttonSpec) []model.InlineKeyBoardButton {
+	row := []model.InlineKeyBoardButton{}
+	for _, spec := range specs {
+		if button, ok := callbackButton(spec.text, spec.prefix, spec.value); ok {
+			row = append(row, button)
+		}
+	}
+	return row
+}
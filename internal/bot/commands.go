@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"alerts/internal/country"
+	"alerts/internal/i18n"
+	"alerts/internal/telegram"
+	"alerts/model"
+	"context"
+	"log"
+	"strings"
+)
+
+// CommandHandler handles a bot command; args is the text after the command.
+type CommandHandler func(ctx context.Context, msg *model.Message, args string) error
+
+var commands = map[string]CommandHandler{
+	"/country":  handleCountryCommand,
+	"/region":   handleRegionCommand,
+	"/language": handleLanguageCommand,
+	"/location": handleLocationCommand,
+	"/watch":    handleWatchCommand,
+	"/filters":  handleFiltersCommand,
+	"/latest":   handleLatestCommand,
+	"/history":  handleHistoryCommand,
+	"/status":   handleStatusCommand,
+}
+
+// HandleMessage runs the command in msg, if any, or saves a shared location
+// as the user's watch location. Plain text is ignored.
+func HandleMessage(ctx context.Context, msg *model.Message) error {
+	if msg.Location != nil {
+		return handleWatchLocation(ctx, msg)
+	}
+	name, args, ok := parseCommand(msg.Text)
+	if !ok {
+		return nil
+	}
+	handler, ok := commands[name]
+	if !ok {
+		return nil
+	}
+	return handler(ctx, msg, args)
+}
+
+// parseCommand splits "/country@SomeBot new zealand" into "/country" and
+// "new zealand".
+func parseCommand(text string) (string, string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	name, args, _ := strings.Cut(text, " ")
+	name, _, _ = strings.Cut(name, "@")
+	return strings.ToLower(name), strings.TrimSpace(args), true
+}
+
+// messageLanguage is the language to answer msg in.
+func messageLanguage(msg *model.Message) string {
+	return userLanguage(msg.Chat.Id, msg.From)
+}
+
+func reply(ctx context.Context, msg *model.Message, text string, keyboard *model.InlineKeyBoardMarkup) error {
+	_, err := telegram.Bot.SendMessage(ctx, &telegram.SendMessageParams{ChatID: msg.Chat.Id, Text: text, ReplyMarkup: keyboard})
+	if err != nil {
+		log.Println("error replying to command:", err)
+	}
+	return err
+}
+
+// handleCountryCommand shows the full picker, or the countries matching
+// args when a name is given.
+func handleCountryCommand(ctx context.Context, msg *model.Message, args string) error {
+	lang := messageLanguage(msg)
+	if args == "" {
+		return reply(ctx, msg, countryPrompt(lang), countryPageKeyboard(lang, 0))
+	}
+	matches := country.Search(args, 8)
+	if len(matches) == 0 {
+		return reply(ctx, msg, i18n.T(lang, "country.no_match", args), nil)
+	}
+	return reply(ctx, msg, i18n.T(lang, "country.did_you_mean"), countrySearchKeyboard(lang, matches))
+}
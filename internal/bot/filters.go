@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"alerts/internal/i18n"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// pagerOptions are the lowest PAGER levels a user can require; "any" turns
+// the filter off.
+var pagerOptions = []string{"any", "yellow", "orange", "red"}
+
+// depthOptions are the deepest events in km a user can ask for; "0" means
+// any depth. 70 km and 300 km are where intermediate and deep quakes start.
+var depthOptions = []string{"0", "70", "150", "300"}
+
+// intensityOptions are the lowest estimated MMI at the watch location a user
+// can ask for, keyed by callback value; "0" means any.
+var intensityOptions = map[string]string{"0": "", "3": "III", "4": "IV", "5": "V"}
+
+const (
+	reviewedOnly = "on"
+	reviewedAny  = "off"
+)
+
+func validatePager(value string) error {
+	if !slices.Contains(pagerOptions, value) {
+		return fmt.Errorf("%w: unsupported alert level %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+func validateReviewed(value string) error {
+	if value != reviewedOnly && value != reviewedAny {
+		return fmt.Errorf("%w: unsupported review setting %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+func validateDepth(value string) error {
+	if !slices.Contains(depthOptions, value) {
+		return fmt.Errorf("%w: unsupported depth %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+func validateIntensity(value string) error {
+	if _, ok := intensityOptions[value]; !ok {
+		return fmt.Errorf("%w: unsupported intensity %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+func filtersKeyboard(lang string) *model.InlineKeyBoardMarkup {
+	return &model.InlineKeyBoardMarkup{
+		InlineKeyBoard: [][]model.InlineKeyBoardButton{
+			keyboardRow(
+				buttonSpec{i18n.T(lang, "button.pager_any"), PrefixPager, "any"},
+				buttonSpec{"🟡+", PrefixPager, "yellow"},
+				buttonSpec{"🟠+", PrefixPager, "orange"},
+				buttonSpec{"🔴", PrefixPager, "red"},
+			),
+			keyboardRow(
+				buttonSpec{i18n.T(lang, "button.reviewed_only"), PrefixReviewed, reviewedOnly},
+				buttonSpec{i18n.T(lang, "button.reviewed_any"), PrefixReviewed, reviewedAny},
+			),
+			keyboardRow(
+				buttonSpec{i18n.T(lang, "button.depth_any"), PrefixDepth, "0"},
+				buttonSpec{"≤70 km", PrefixDepth, "70"},
+				buttonSpec{"≤150 km", PrefixDepth, "150"},
+				buttonSpec{"≤300 km", PrefixDepth, "300"},
+			),
+			keyboardRow(
+				buttonSpec{i18n.T(lang, "button.intensity_any"), PrefixIntensity, "0"},
+				buttonSpec{"🏠 III+", PrefixIntensity, "3"},
+				buttonSpec{"🏠 IV+", PrefixIntensity, "4"},
+				buttonSpec{"🏠 V+", PrefixIntensity, "5"},
+			),
+		},
+	}
+}
+
+func handleFiltersCommand(ctx context.Context, msg *model.Message, _ string) error {
+	lang := messageLanguage(msg)
+	return reply(ctx, msg, i18n.T(lang, "filters.prompt"), filtersKeyboard(lang))
+}
+
+func handlePager(ctx context.Context, query *model.CallbackQuery, value string) error {
+	level := value
+	if level == "any" {
+		level = ""
+	}
+	if err := repository.UpdateMinAlert(level, queryChatId(query)); err != nil {
+		return fmt.Errorf("error updating the alert level filter: %w", err)
+	}
+	lang := queryLanguage(query)
+	text := i18n.T(lang, "filters.pager_any")
+	if level != "" {
+		text = i18n.T(lang, "filters.pager_set", i18n.T(lang, "alert.pager_"+level))
+	}
+	answerCallback(ctx, query, i18n.T(lang, "filters.saved"))
+	replaceKeyboardMessage(ctx, query, text, nil)
+	return nil
+}
+
+func handleReviewed(ctx context.Context, query *model.CallbackQuery, value string) error {
+	enabled := value == reviewedOnly
+	if err := repository.UpdateReviewedOnly(enabled, queryChatId(query)); err != nil {
+		return fmt.Errorf("error updating the review filter: %w", err)
+	}
+	lang := queryLanguage(query)
+	text := i18n.T(lang, "filters.reviewed_any")
+	if enabled {
+		text = i18n.T(lang, "filters.reviewed_only")
+	}
+	answerCallback(ctx, query, i18n.T(lang, "filters.saved"))
+	replaceKeyboardMessage(ctx, query, text, nil)
+	return nil
+}
+
+func handleDepth(ctx context.Context, query *model.CallbackQuery, value string) error {
+	depth, _ := strconv.ParseFloat(value, 64)
+	if err := repository.UpdateMaxDepth(depth, queryChatId(query)); err != nil {
+		return fmt.Errorf("error updating the depth filter: %w", err)
+	}
+	lang := queryLanguage(query)
+	text := i18n.T(lang, "filters.depth_any")
+	if depth > 0 {
+		text = i18n.T(lang, "filters.depth_set", value)
+	}
+	answerCallback(ctx, query, i18n.T(lang, "filters.saved"))
+	replaceKeyboardMessage(ctx, query, text, nil)
+	return nil
+}
+
+func handleIntensity(ctx context.Context, query *model.CallbackQuery, value string) error {
+	intensity, _ := strconv.ParseFloat(value, 64)
+	chatId := queryChatId(query)
+	lang := queryLanguage(query)
+	// Without a watch location there is nowhere to estimate shaking at, so
+	// the filter would silently do nothing.
+	if intensity > 0 {
+		preferences, err := repository.GetUserPreferences(chatId)
+		if err != nil {
+			return fmt.Errorf("error loading the preferences: %w", err)
+		}
+		if preferences.Watch == nil {
+			text := i18n.T(lang, "filters.intensity_no_watch")
+			answerCallback(ctx, query, text)
+			replaceKeyboardMessage(ctx, query, text, nil)
+			return nil
+		}
+	}
+	if err := repository.UpdateMinIntensity(intensity, chatId); err != nil {
+		return fmt.Errorf("error updating the intensity filter: %w", err)
+	}
+	text := i18n.T(lang, "filters.intensity_any")
+	if intensity > 0 {
+		text = i18n.T(lang, "filters.intensity_set", intensityOptions[value])
+	}
+	answerCallback(ctx, query, i18n.T(lang, "filters.saved"))
+	replaceKeyboardMessage(ctx, query, text, nil)
+	return nil
+}
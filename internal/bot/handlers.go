@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"alerts/internal/country"
+	"alerts/internal/i18n"
+	"alerts/internal/telegram"
+	"alerts/internal/templates"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	PrefixCountry     = "cty"
+	PrefixCountryPage = "cpg"
+	PrefixRegion      = "rgn"
+	PrefixLanguage    = "lng"
+	PrefixLocation    = "loc"
+	PrefixPager       = "pgr"
+	PrefixReviewed    = "rvw"
+	PrefixDepth       = "dep"
+	PrefixIntensity   = "mmi"
+	PrefixMagnitude   = "mag"
+	PrefixUnsubscribe = "unsub"
+	PrefixLatest      = "lst"
+	PrefixHistory     = "hst"
+)
+
+// magnitudeOptions are the minimum magnitudes a user can pick; "0" means any.
+var magnitudeOptions = []string{"0", "4.5", "5.0", "6.0", "7.0"}
+
+var callbacks = NewDispatcher()
+
+func init() {
+	callbacks.Register(PrefixCountry, validateCountry, handleCountry)
+	callbacks.Register(PrefixCountryPage, validateCountryPage, handleCountryPage)
+	callbacks.Register(PrefixRegion, validateRegion, handleRegion)
+	callbacks.Register(PrefixLanguage, validateLanguage, handleLanguage)
+	callbacks.Register(PrefixLocation, validateLocation, handleLocation)
+	callbacks.Register(PrefixPager, validatePager, handlePager)
+	callbacks.Register(PrefixReviewed, validateReviewed, handleReviewed)
+	callbacks.Register(PrefixDepth, validateDepth, handleDepth)
+	callbacks.Register(PrefixIntensity, validateIntensity, handleIntensity)
+	callbacks.Register(PrefixMagnitude, validateMagnitude, handleMagnitude)
+	callbacks.Register(PrefixUnsubscribe, nil, handleUnsubscribe)
+	callbacks.Register(PrefixLatest, validateLatestPage, handleLatestPage)
+	callbacks.Register(PrefixHistory, validateHistoryPage, handleHistoryPage)
+}
+
+func validateCountry(value string) error {
+	if !country.Valid(value) {
+		return fmt.Errorf("%w: unknown country %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+func validateMagnitude(value string) error {
+	if !slices.Contains(magnitudeOptions, value) {
+		return fmt.Errorf("%w: unsupported magnitude %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+// HandleCallbackQuery dispatches a tapped inline button to its handler.
+func HandleCallbackQuery(ctx context.Context, query *model.CallbackQuery) error {
+	// Keyboards sent before callback data was namespaced carry a bare
+	// country code; route those to the country handler.
+	if !strings.Contains(query.Data, callbackSeparator) && validateCountry(query.Data) == nil {
+		query.Data = PrefixCountry + callbackSeparator + query.Data
+	}
+	return callbacks.Dispatch(ctx, query)
+}
+
+// countryPrompt is the text shown above the country keyboard.
+func countryPrompt(lang string) string {
+	text, err := templates.Render(templates.Keyboard, lang, nil)
+	if err != nil {
+		log.Println("error rendering the keyboard text:", err)
+		return i18n.T(lang, "keyboard.prompt")
+	}
+	return text
+}
+
+// countryLabel is the localized display name of a stored country code.
+func countryLabel(lang, code string) string {
+	if code == country.Global {
+		return i18n.T(lang, "country.world")
+	}
+	return country.DisplayName(code)
+}
+
+// SendKeyBoard asks the user to pick the country they want alerts for.
+func SendKeyBoard(ctx context.Context, chatId int64) error {
+	lang := userLanguage(chatId, nil)
+	msg := &telegram.SendMessageParams{
+		ChatID:      chatId,
+		Text:        countryPrompt(lang),
+		ReplyMarkup: countryPageKeyboard(lang, 0),
+	}
+	if _, err := telegram.Bot.SendMessage(ctx, msg); err != nil {
+		log.Println("error sending keyboard to Telegram:", err)
+		return err
+	}
+	return nil
+}
+
+// settingsKeyboard is shown once a country is picked so the user can tune or
+// stop their alerts.
+func settingsKeyboard(lang string) *model.InlineKeyBoardMarkup {
+	return &model.InlineKeyBoardMarkup{
+		InlineKeyBoard: [][]model.InlineKeyBoardButton{
+			keyboardRow(
+				buttonSpec{i18n.T(lang, "button.any_magnitude"), PrefixMagnitude, "0"},
+				buttonSpec{"M4.5+", PrefixMagnitude, "4.5"},
+				buttonSpec{"M5+", PrefixMagnitude, "5.0"},
+			),
+			keyboardRow(
+				buttonSpec{"M6+", PrefixMagnitude, "6.0"},
+				buttonSpec{"M7+", PrefixMagnitude, "7.0"},
+			),
+			locationRow(lang),
+			keyboardRow(
+				buttonSpec{i18n.T(lang, "button.unsubscribe"), PrefixUnsubscribe, ""},
+			),
+		},
+	}
+}
+
+// handleCountry saves the tapped country, answers the query so the client
+// stops showing a spinner on the button, and replaces the keyboard message
+// with the confirmed selection so it can't be tapped again.
+func handleCountry(ctx context.Context, query *model.CallbackQuery, code string) error {
+	if err := repository.UpdateCountryPreference(code, queryChatId(query)); err != nil {
+		log.Println("error updating the database")
+		return fmt.Errorf("error updating the country preference: %w", err)
+	}
+	lang := queryLanguage(query)
+	countryName := countryLabel(lang, code)
+	answerCallback(ctx, query, i18n.T(lang, "country.saved", countryName))
+	replaceKeyboardMessage(ctx, query, i18n.T(lang, "country.selected", countryName), settingsKeyboard(lang))
+	return nil
+}
+
+// handleCountryPage flips the picker to another page in place.
+func handleCountryPage(ctx context.Context, query *model.CallbackQuery, value string) error {
+	page, _ := strconv.Atoi(value)
+	lang := queryLanguage(query)
+	answerCallback(ctx, query, "")
+	replaceKeyboardMessage(ctx, query, countryPrompt(lang), countryPageKeyboard(lang, page))
+	return nil
+}
+
+func handleMagnitude(ctx context.Context, query *model.CallbackQuery, value string) error {
+	magnitude, _ := strconv.ParseFloat(value, 64)
+	if err := repository.UpdateMinMagnitude(magnitude, queryChatId(query)); err != nil {
+		return fmt.Errorf("error updating the minimum magnitude: %w", err)
+	}
+	lang := queryLanguage(query)
+	text := i18n.T(lang, "magnitude.set", value)
+	if magnitude == 0 {
+		text = i18n.T(lang, "magnitude.any")
+	}
+	answerCallback(ctx, query, i18n.T(lang, "magnitude.saved"))
+	replaceKeyboardMessage(ctx, query, text, nil)
+	return nil
+}
+
+func handleUnsubscribe(ctx context.Context, query *model.CallbackQuery, _ string) error {
+	if err := repository.Unsubscribe(queryChatId(query)); err != nil {
+		return fmt.Errorf("error unsubscribing: %w", err)
+	}
+	lang := queryLanguage(query)
+	answerCallback(ctx, query, i18n.T(lang, "unsubscribe.toast"))
+	replaceKeyboardMessage(ctx, query, i18n.T(lang, "unsubscribe.done"), nil)
+	return nil
+}
+
+// replaceKeyboardMessage edits the message the tapped keyboard belongs to,
+// falling back to a new message when Telegram didn't include it.
+func replaceKeyboardMessage(ctx context.Context, query *model.CallbackQuery, text string, keyboard *model.InlineKeyBoardMarkup) {
+	if query.Message != nil && query.Message.Chat != nil {
+		edit := &telegram.EditMessageTextParams{
+			ChatID:      query.Message.Chat.Id,
+			MessageID:   query.Message.MessageID,
+			Text:        text,
+			ReplyMarkup: keyboard,
+		}
+		if err := telegram.Bot.EditMessageText(ctx, edit); err != nil {
+			log.Println("error editing the keyboard message:", err)
+		}
+		return
+	}
+	if _, err := telegram.Bot.SendMessage(ctx, &telegram.SendMessageParams{ChatID: query.From.Id, Text: text, ReplyMarkup: keyboard}); err != nil {
+		log.Println("error sending the confirmation:", err)
+	}
+}
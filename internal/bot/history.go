@@ -0,0 +1,271 @@
+package bot
+
+import (
+	"alerts/config"
+	"alerts/internal/country"
+	"alerts/internal/filter"
+	"alerts/internal/i18n"
+	"alerts/internal/telegram"
+	"alerts/internal/templates"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLatest      = 5
+	maxLatest          = 20
+	defaultHistoryDays = 7
+	historyPageSize    = 10
+	// maxHistoryQuery keeps a place within Telegram's callback data limit.
+	maxHistoryQuery = 40
+	// maxPage only bounds callback data; listings end long before it.
+	maxPage = 1000
+)
+
+// latestPage is the callback value of a /latest page: "<n>,<page>".
+type latestPage struct {
+	n    int
+	page int
+}
+
+func parseLatestPage(value string) (*latestPage, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("%w: bad latest page %q", ErrInvalidCallback, value)
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 || n > maxLatest {
+		return nil, fmt.Errorf("%w: bad latest count %q", ErrInvalidCallback, value)
+	}
+	page, err := strconv.Atoi(fields[1])
+	if err != nil || page < 0 || page >= maxPage {
+		return nil, fmt.Errorf("%w: bad latest page %q", ErrInvalidCallback, value)
+	}
+	return &latestPage{n: n, page: page}, nil
+}
+
+func (p *latestPage) value(page int) string {
+	return fmt.Sprintf("%d,%d", p.n, page)
+}
+
+// historyPage is the callback value of a /history page:
+// "<page>,<days>,<country code or place>". The place goes last as it may
+// contain commas.
+type historyPage struct {
+	page  int
+	days  int
+	query string
+}
+
+func parseHistoryPage(value string) (*historyPage, error) {
+	fields := strings.SplitN(value, ",", 3)
+	if len(fields) != 3 || fields[2] == "" {
+		return nil, fmt.Errorf("%w: bad history page %q", ErrInvalidCallback, value)
+	}
+	page, err := strconv.Atoi(fields[0])
+	if err != nil || page < 0 || page >= maxPage {
+		return nil, fmt.Errorf("%w: bad history page %q", ErrInvalidCallback, value)
+	}
+	days, err := strconv.Atoi(fields[1])
+	if err != nil || days < 1 {
+		return nil, fmt.Errorf("%w: bad history days %q", ErrInvalidCallback, value)
+	}
+	return &historyPage{page: page, days: days, query: fields[2]}, nil
+}
+
+func (p *historyPage) value(page int) string {
+	return fmt.Sprintf("%d,%d,%s", page, p.days, p.query)
+}
+
+func validateLatestPage(value string) error {
+	_, err := parseLatestPage(value)
+	return err
+}
+
+func validateHistoryPage(value string) error {
+	_, err := parseHistoryPage(value)
+	return err
+}
+
+// handleLatestCommand lists the n most recent stored events that would have
+// been alerted to the user.
+func handleLatestCommand(ctx context.Context, msg *model.Message, args string) error {
+	lang := messageLanguage(msg)
+	p := &latestPage{n: defaultLatest}
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 || n > maxLatest {
+			return reply(ctx, msg, i18n.T(lang, "latest.usage", maxLatest), nil)
+		}
+		p.n = n
+	}
+	text, keyboard, err := renderLatest(msg.Chat.Id, lang, p)
+	if err != nil {
+		return err
+	}
+	return showPage(ctx, msg.Chat.Id, nil, text, keyboard)
+}
+
+// handleHistoryCommand lists the stored events in a country, or whose place
+// names contain the given text, over the last days.
+func handleHistoryCommand(ctx context.Context, msg *model.Message, args string) error {
+	lang := messageLanguage(msg)
+	retention := config.BotConf.EventRetentionDays
+	fields := strings.Fields(args)
+	days := defaultHistoryDays
+	if len(fields) > 1 {
+		if value, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+			days = value
+			fields = fields[:len(fields)-1]
+		}
+	}
+	query := strings.Join(fields, " ")
+	if c, ok := country.Find(query); ok {
+		query = c.Code
+	}
+	p := &historyPage{days: min(days, retention), query: query}
+	if query == "" || len(query) > maxHistoryQuery || days < 1 {
+		return reply(ctx, msg, i18n.T(lang, "history.usage", retention), nil)
+	}
+	text, keyboard, err := renderHistory(lang, p)
+	if err != nil {
+		return err
+	}
+	return showPage(ctx, msg.Chat.Id, nil, text, keyboard)
+}
+
+func handleLatestPage(ctx context.Context, query *model.CallbackQuery, value string) error {
+	p, _ := parseLatestPage(value)
+	chatId := queryChatId(query)
+	answerCallback(ctx, query, "")
+	text, keyboard, err := renderLatest(chatId, queryLanguage(query), p)
+	if err != nil {
+		return err
+	}
+	return showPage(ctx, chatId, query, text, keyboard)
+}
+
+func handleHistoryPage(ctx context.Context, query *model.CallbackQuery, value string) error {
+	p, _ := parseHistoryPage(value)
+	answerCallback(ctx, query, "")
+	text, keyboard, err := renderHistory(queryLanguage(query), p)
+	if err != nil {
+		return err
+	}
+	return showPage(ctx, queryChatId(query), query, text, keyboard)
+}
+
+// renderLatest filters the retained events with the user's country, regions,
+// minimum magnitude and filters, as the alert loop does.
+func renderLatest(chatId int64, lang string, p *latestPage) (string, *model.InlineKeyBoardMarkup, error) {
+	countryCode, err := repository.GetCountry(chatId)
+	if err != nil {
+		return "", nil, err
+	}
+	regions, err := filter.UserRegions(chatId)
+	if err != nil {
+		return "", nil, err
+	}
+	preferences, err := repository.GetUserPreferences(chatId)
+	if err != nil {
+		return "", nil, err
+	}
+	retention := config.BotConf.EventRetentionDays
+	now := time.Now()
+	stored, err := repository.GetEventsBetween(now.AddDate(0, 0, -retention), now)
+	if err != nil {
+		return "", nil, err
+	}
+	slices.Reverse(stored)
+	matching := []*model.Event{}
+	for _, event := range stored {
+		if event.Magnitude >= preferences.MinMagnitude && filter.Passes(preferences, event) && filter.Covers(countryCode, regions, event, event.CountryCode) {
+			matching = append(matching, event)
+		}
+	}
+	start := min(p.page*p.n, len(matching))
+	events := matching[start:min(start+p.n, len(matching))]
+	if len(events) == 0 {
+		return templates.Mode().Escape(i18n.T(lang, "latest.none", retention)), nil, nil
+	}
+	text, err := renderListing(lang, i18n.T(lang, "latest.title"), p.page, events)
+	keyboard := pageKeyboard(lang, PrefixLatest, p.page, start+p.n < len(matching), p.value)
+	return text, keyboard, err
+}
+
+func renderHistory(lang string, p *historyPage) (string, *model.InlineKeyBoardMarkup, error) {
+	countryCode, place, label := "", p.query, strconv.Quote(p.query)
+	if c, ok := country.Lookup(p.query); ok {
+		countryCode, place, label = c.Code, "", countryLabel(lang, c.Code)
+	}
+	from := time.Now().AddDate(0, 0, -p.days)
+	// One extra event tells whether there is a next page.
+	events, err := repository.GetEventHistory(countryCode, place, from, historyPageSize+1, p.page*historyPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(events) == 0 {
+		return templates.Mode().Escape(i18n.T(lang, "history.none", label, p.days)), nil, nil
+	}
+	more := len(events) > historyPageSize
+	events = events[:min(len(events), historyPageSize)]
+	text, err := renderListing(lang, i18n.T(lang, "history.title", label, p.days), p.page, events)
+	keyboard := pageKeyboard(lang, PrefixHistory, p.page, more, p.value)
+	return text, keyboard, err
+}
+
+// renderListing lists events with the digest template, numbering pages after
+// the first.
+func renderListing(lang, title string, page int, events []*model.Event) (string, error) {
+	if page > 0 {
+		title = fmt.Sprintf("%s · %s", title, i18n.T(lang, "listing.page", page+1))
+	}
+	return templates.Render(templates.Digest, lang, &templates.DigestData{Title: title, Events: events})
+}
+
+// pageKeyboard holds the previous and next buttons of a listing, or is nil
+// when everything fits on one page. value encodes the callback value of a page.
+func pageKeyboard(lang, prefix string, page int, more bool, value func(page int) string) *model.InlineKeyBoardMarkup {
+	navigation := []buttonSpec{}
+	if page > 0 {
+		navigation = append(navigation, buttonSpec{i18n.T(lang, "button.prev"), prefix, value(page - 1)})
+	}
+	if more {
+		navigation = append(navigation, buttonSpec{i18n.T(lang, "button.next"), prefix, value(page + 1)})
+	}
+	if len(navigation) == 0 {
+		return nil
+	}
+	return &model.InlineKeyBoardMarkup{InlineKeyBoard: [][]model.InlineKeyBoardButton{keyboardRow(navigation...)}}
+}
+
+// showPage sends a listing in the template parse mode, or edits it in place
+// when a page button was tapped.
+func showPage(ctx context.Context, chatId int64, query *model.CallbackQuery, text string, keyboard *model.InlineKeyBoardMarkup) error {
+	mode := string(templates.Mode())
+	if query != nil && query.Message != nil {
+		edit := &telegram.EditMessageTextParams{
+			ChatID:      chatId,
+			MessageID:   query.Message.MessageID,
+			Text:        text,
+			ParseMode:   mode,
+			ReplyMarkup: keyboard,
+		}
+		if err := telegram.Bot.EditMessageText(ctx, edit); err != nil {
+			log.Println("error editing the listing:", err)
+		}
+		return nil
+	}
+	_, err := telegram.Bot.SendMessage(ctx, &telegram.SendMessageParams{ChatID: chatId, Text: text, ParseMode: mode, ReplyMarkup: keyboard})
+	if err != nil {
+		log.Println("error sending the listing:", err)
+	}
+	return err
+}
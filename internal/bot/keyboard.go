@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"alerts/internal/country"
+	"alerts/internal/i18n"
+	"alerts/model"
+	"fmt"
+	"strconv"
+)
+
+const (
+	countryColumns  = 2
+	countryPageSize = 16
+)
+
+func countryPageCount() int {
+	return (len(country.All()) + countryPageSize - 1) / countryPageSize
+}
+
+func validateCountryPage(value string) error {
+	page, err := strconv.Atoi(value)
+	if err != nil || page < 0 || page >= countryPageCount() {
+		return fmt.Errorf("%w: bad country page %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+// countryPageKeyboard lists one alphabetical page of countries, with Global on
+// top and navigation buttons labelled with the letters they lead to.
+func countryPageKeyboard(lang string, page int) *model.InlineKeyBoardMarkup {
+	all := country.All()
+	start := page * countryPageSize
+	end := min(start+countryPageSize, len(all))
+
+	rows := [][]model.InlineKeyBoardButton{
+		keyboardRow(buttonSpec{i18n.T(lang, "button.global"), PrefixCountry, country.Global}),
+	}
+	rows = append(rows, countryRows(all[start:end])...)
+
+	navigation := []buttonSpec{}
+	if page > 0 {
+		navigation = append(navigation, buttonSpec{"◀ " + pageLetters(page-1), PrefixCountryPage, strconv.Itoa(page - 1)})
+	}
+	if page < countryPageCount()-1 {
+		navigation = append(navigation, buttonSpec{pageLetters(page+1) + " ▶", PrefixCountryPage, strconv.Itoa(page + 1)})
+	}
+	rows = append(rows, keyboardRow(navigation...))
+	return &model.InlineKeyBoardMarkup{InlineKeyBoard: rows}
+}
+
+// countrySearchKeyboard offers the matches of a /country search plus a way
+// back to the full list.
+func countrySearchKeyboard(lang string, matches []*country.Country) *model.InlineKeyBoardMarkup {
+	rows := countryRows(matches)
+	rows = append(rows, keyboardRow(buttonSpec{i18n.T(lang, "button.browse_countries"), PrefixCountryPage, "0"}))
+	return &model.InlineKeyBoardMarkup{InlineKeyBoard: rows}
+}
+
+func countryRows(countries []*country.Country) [][]model.InlineKeyBoardButton {
+	rows := [][]model.InlineKeyBoardButton{}
+	for i := 0; i < len(countries); i += countryColumns {
+		specs := []buttonSpec{}
+		for _, c := range countries[i:min(i+countryColumns, len(countries))] {
+			specs = append(specs, buttonSpec{c.Flag() + " " + c.Name, PrefixCountry, c.Code})
+		}
+		rows = append(rows, keyboardRow(specs...))
+	}
+	return rows
+}
+
+// pageLetters describes a page by the first letters of its first and last
+// country, e.g. "C–D".
+func pageLetters(page int) string {
+	all := country.All()
+	first := all[page*countryPageSize].Initial()
+	last := all[min((page+1)*countryPageSize, len(all))-1].Initial()
+	if first == last {
+		return first
+	}
+	return first + "–" + last
+}
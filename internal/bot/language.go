@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"alerts/internal/i18n"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+	"log"
+)
+
+func validateLanguage(value string) error {
+	if !i18n.Supported(value) {
+		return fmt.Errorf("%w: unsupported language %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+// userLanguage is the language chosen with /language, else the one Telegram
+// reports for the user, else English.
+func userLanguage(chatId int64, from *model.User) string {
+	lang, err := repository.GetLanguage(chatId)
+	if err != nil {
+		log.Println("error fetching the user language:", err)
+	}
+	if lang == "" && from != nil {
+		lang = from.LanguageCode
+	}
+	return i18n.Resolve(lang)
+}
+
+func queryLanguage(query *model.CallbackQuery) string {
+	if query.From == nil {
+		return i18n.Default
+	}
+	return userLanguage(queryChatId(query), query.From)
+}
+
+func languageKeyboard() *model.InlineKeyBoardMarkup {
+	rows := [][]model.InlineKeyBoardButton{}
+	for _, language := range i18n.Languages {
+		rows = append(rows, keyboardRow(buttonSpec{language.Name, PrefixLanguage, language.Code}))
+	}
+	return &model.InlineKeyBoardMarkup{InlineKeyBoard: rows}
+}
+
+func handleLanguageCommand(ctx context.Context, msg *model.Message, _ string) error {
+	return reply(ctx, msg, i18n.T(messageLanguage(msg), "language.prompt"), languageKeyboard())
+}
+
+func handleLanguage(ctx context.Context, query *model.CallbackQuery, lang string) error {
+	if err := repository.UpdateLanguage(lang, queryChatId(query)); err != nil {
+		return fmt.Errorf("error updating the language: %w", err)
+	}
+	text := i18n.T(lang, "language.set")
+	answerCallback(ctx, query, text)
+	replaceKeyboardMessage(ctx, query, text, nil)
+	return nil
+}
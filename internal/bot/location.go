@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"alerts/internal/i18n"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+)
+
+const (
+	locationOn  = "on"
+	locationOff = "off"
+)
+
+func validateLocation(value string) error {
+	if value != locationOn && value != locationOff {
+		return fmt.Errorf("%w: unsupported location setting %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+// locationRow lets the user choose whether alerts are followed by a native
+// Telegram map pin of the epicenter.
+func locationRow(lang string) []model.InlineKeyBoardButton {
+	return keyboardRow(
+		buttonSpec{i18n.T(lang, "button.location_on"), PrefixLocation, locationOn},
+		buttonSpec{i18n.T(lang, "button.location_off"), PrefixLocation, locationOff},
+	)
+}
+
+func handleLocationCommand(ctx context.Context, msg *model.Message, _ string) error {
+	lang := messageLanguage(msg)
+	keyboard := &model.InlineKeyBoardMarkup{InlineKeyBoard: [][]model.InlineKeyBoardButton{locationRow(lang)}}
+	return reply(ctx, msg, i18n.T(lang, "location.prompt"), keyboard)
+}
+
+func handleLocation(ctx context.Context, query *model.CallbackQuery, value string) error {
+	enabled := value == locationOn
+	if err := repository.UpdateSendLocation(enabled, queryChatId(query)); err != nil {
+		return fmt.Errorf("error updating the location setting: %w", err)
+	}
+	lang := queryLanguage(query)
+	text := i18n.T(lang, "location.off")
+	if enabled {
+		text = i18n.T(lang, "location.on")
+	}
+	answerCallback(ctx, query, text)
+	replaceKeyboardMessage(ctx, query, text, nil)
+	return nil
+}
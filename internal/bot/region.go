@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"alerts/internal/i18n"
+	"alerts/internal/region"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func validateRegion(value string) error {
+	if _, ok := region.Lookup(value); !ok {
+		return fmt.Errorf("%w: unknown region %q", ErrInvalidCallback, value)
+	}
+	return nil
+}
+
+func regionName(lang string, r *region.Region) string {
+	return i18n.T(lang, "region."+r.Id)
+}
+
+func regionKeyboard(lang string, subscribed []string) *model.InlineKeyBoardMarkup {
+	rows := [][]model.InlineKeyBoardButton{}
+	for _, r := range region.All() {
+		label := regionName(lang, r)
+		if slices.Contains(subscribed, r.Id) {
+			label = "✅ " + label
+		}
+		rows = append(rows, keyboardRow(buttonSpec{label, PrefixRegion, r.Id}))
+	}
+	return &model.InlineKeyBoardMarkup{InlineKeyBoard: rows}
+}
+
+func handleRegionCommand(ctx context.Context, msg *model.Message, args string) error {
+	chatId := msg.Chat.Id
+	lang := messageLanguage(msg)
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		subscribed, err := repository.GetUserRegions(chatId)
+		if err != nil {
+			return err
+		}
+		return reply(ctx, msg, i18n.T(lang, "region.prompt"), regionKeyboard(lang, subscribed))
+	case fields[0] == "clear" && len(fields) == 1:
+		if err := repository.ClearUserRegions(chatId); err != nil {
+			return err
+		}
+		return reply(ctx, msg, i18n.T(lang, "region.cleared"), nil)
+	case fields[0] == "box" && len(fields) == 5:
+		values := make([]float64, 4)
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return reply(ctx, msg, i18n.T(lang, "region.usage"), nil)
+			}
+			values[i] = value
+		}
+		ref, err := region.Custom(region.Box{MinLatitude: values[0], MinLongitude: values[1], MaxLatitude: values[2], MaxLongitude: values[3]})
+		if err != nil {
+			return reply(ctx, msg, i18n.T(lang, "region.invalid_area", err), nil)
+		}
+		if err = repository.AddUserRegion(chatId, ref); err != nil {
+			return err
+		}
+		return reply(ctx, msg, i18n.T(lang, "region.custom_subscribed", values[0], values[1], values[2], values[3]), nil)
+	}
+	return reply(ctx, msg, i18n.T(lang, "region.usage"), nil)
+}
+
+// handleRegion toggles a predefined region and redraws the keyboard.
+func handleRegion(ctx context.Context, query *model.CallbackQuery, id string) error {
+	chatId := queryChatId(query)
+	lang := queryLanguage(query)
+	subscribed, err := repository.GetUserRegions(chatId)
+	if err != nil {
+		return err
+	}
+	r, _ := region.Lookup(id)
+	if slices.Contains(subscribed, id) {
+		if err = repository.RemoveUserRegion(chatId, id); err != nil {
+			return err
+		}
+		subscribed = slices.DeleteFunc(subscribed, func(s string) bool { return s == id })
+		answerCallback(ctx, query, i18n.T(lang, "region.removed", regionName(lang, r)))
+	} else {
+		if err = repository.AddUserRegion(chatId, id); err != nil {
+			return err
+		}
+		subscribed = append(subscribed, id)
+		answerCallback(ctx, query, i18n.T(lang, "region.added", regionName(lang, r)))
+	}
+	replaceKeyboardMessage(ctx, query, i18n.T(lang, "region.prompt"), regionKeyboard(lang, subscribed))
+	return nil
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"alerts/config"
+	"alerts/internal/filter"
+	"alerts/internal/health"
+	"alerts/internal/i18n"
+	"alerts/internal/region"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// feedCheck is the scheduler's polling check; Register hands back the same
+// check for the same name.
+var feedCheck = health.Register("earthquake_poll")
+
+// handleStatusCommand summarises what the chat is subscribed to, when it
+// last got an alert and when the feed was last polled successfully.
+func handleStatusCommand(ctx context.Context, msg *model.Message, _ string) error {
+	chatId := msg.Chat.Id
+	lang := messageLanguage(msg)
+	countryCode, err := repository.GetCountry(chatId)
+	if err != nil {
+		return err
+	}
+	regions, err := filter.UserRegions(chatId)
+	if err != nil {
+		return err
+	}
+	preferences, err := repository.GetUserPreferences(chatId)
+	if err != nil {
+		return err
+	}
+	lastTitle, lastAt, err := repository.GetLastAlert(chatId)
+	if err != nil {
+		return err
+	}
+
+	none := i18n.T(lang, "status.none")
+	lines := []string{i18n.T(lang, "status.title"), ""}
+
+	countryName := none
+	if countryCode != "" {
+		countryName = countryLabel(lang, countryCode)
+	}
+	lines = append(lines, i18n.T(lang, "status.country", countryName))
+
+	regionNames := []string{}
+	for _, r := range regions {
+		if _, ok := region.Lookup(r.Id); ok {
+			regionNames = append(regionNames, regionName(lang, r))
+		} else {
+			regionNames = append(regionNames, r.Name)
+		}
+	}
+	lines = append(lines, i18n.T(lang, "status.regions", joinOr(regionNames, none)))
+
+	watch := none
+	if preferences.Watch != nil {
+		watch = fmt.Sprintf("%.4f, %.4f", preferences.Watch.Latitude, preferences.Watch.Longitude)
+	}
+	lines = append(lines, i18n.T(lang, "status.watch", watch))
+
+	magnitude := i18n.T(lang, "status.any")
+	if preferences.MinMagnitude > 0 {
+		magnitude = "M" + strconv.FormatFloat(preferences.MinMagnitude, 'f', 1, 64)
+	}
+	lines = append(lines, i18n.T(lang, "status.magnitude", magnitude))
+	lines = append(lines, i18n.T(lang, "status.filters", joinOr(statusFilters(lang, preferences), none)))
+	lines = append(lines, i18n.T(lang, "status.delivery", strings.Join(statusDelivery(lang, preferences), ", ")))
+	lines = append(lines, i18n.T(lang, "status.language", languageName(lang)))
+
+	lines = append(lines, "")
+	lastAlert := i18n.T(lang, "status.never")
+	if !lastAt.IsZero() {
+		lastAlert = fmt.Sprintf("%s (%s)", lastTitle, i18n.Date(lang, "date.short", lastAt.UTC()))
+	}
+	lines = append(lines, i18n.T(lang, "status.last_alert", lastAlert))
+
+	feed := feedCheck.Status()
+	lastPoll := i18n.T(lang, "status.never")
+	if !feed.LastSuccess.IsZero() {
+		lastPoll = i18n.Date(lang, "date.short", feed.LastSuccess.UTC())
+	}
+	lines = append(lines, i18n.T(lang, "status.feed", lastPoll))
+	if feed.ConsecutiveFailures > 0 {
+		lines = append(lines, i18n.N(lang, "status.feed_failing", feed.ConsecutiveFailures))
+	}
+	return reply(ctx, msg, strings.Join(lines, "\n"), nil)
+}
+
+// statusFilters describes the optional alert filters that are switched on.
+func statusFilters(lang string, preferences *model.UserPreferences) []string {
+	filters := []string{}
+	if preferences.MinAlert != "" {
+		filters = append(filters, i18n.T(lang, "status.filter_pager", i18n.T(lang, "alert.pager_"+preferences.MinAlert)))
+	}
+	if preferences.ReviewedOnly {
+		filters = append(filters, i18n.T(lang, "status.filter_reviewed"))
+	}
+	if preferences.MaxDepth > 0 {
+		filters = append(filters, i18n.T(lang, "status.filter_depth", strconv.FormatFloat(preferences.MaxDepth, 'f', -1, 64)))
+	}
+	if preferences.MinIntensity > 0 {
+		filters = append(filters, i18n.T(lang, "status.filter_intensity", intensityOptions[strconv.FormatFloat(preferences.MinIntensity, 'f', -1, 64)]))
+	}
+	return filters
+}
+
+// statusDelivery describes how alerts reach the chat. Maps and aftershock
+// summaries are set for the whole bot rather than per user.
+func statusDelivery(lang string, preferences *model.UserPreferences) []string {
+	delivery := []string{i18n.T(lang, "status.delivery_instant")}
+	if config.BotConf.AlertMap {
+		delivery = append(delivery, i18n.T(lang, "status.delivery_map"))
+	}
+	if preferences.SendLocation {
+		delivery = append(delivery, i18n.T(lang, "status.delivery_pin"))
+	}
+	if config.BotConf.AftershockSummary > 0 {
+		magnitude := strconv.FormatFloat(config.BotConf.AftershockSummary, 'f', 1, 64)
+		delivery = append(delivery, i18n.T(lang, "status.delivery_summary", magnitude, config.BotConf.SummaryInterval))
+	}
+	return delivery
+}
+
+func languageName(lang string) string {
+	for _, language := range i18n.Languages {
+		if language.Code == lang {
+			return language.Name
+		}
+	}
+	return lang
+}
+
+// joinOr joins values with commas, or returns empty when there are none.
+func joinOr(values []string, empty string) string {
+	if len(values) == 0 {
+		return empty
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"alerts/internal/i18n"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+)
+
+// handleWatchCommand explains how to set a watch location, or removes it
+// with "/watch clear".
+func handleWatchCommand(ctx context.Context, msg *model.Message, args string) error {
+	lang := messageLanguage(msg)
+	if args != "clear" {
+		usage := i18n.T(lang, "watch.usage")
+		if !privateChat(msg.Chat) {
+			usage += "\n\n" + i18n.T(lang, "watch.group")
+		}
+		return reply(ctx, msg, usage, nil)
+	}
+	if err := repository.ClearWatchLocation(msg.Chat.Id); err != nil {
+		return fmt.Errorf("error clearing the watch location: %w", err)
+	}
+	return reply(ctx, msg, i18n.T(lang, "watch.cleared"), nil)
+}
+
+// handleWatchLocation saves a location the user shared as the place they
+// want to follow, e.g. their home. In groups, where members share locations
+// with each other, only a reply to the bot counts.
+func handleWatchLocation(ctx context.Context, msg *model.Message) error {
+	if !privateChat(msg.Chat) && (msg.ReplyToMessage == nil || msg.ReplyToMessage.From == nil || !msg.ReplyToMessage.From.IsBot) {
+		return nil
+	}
+	if err := repository.UpdateWatchLocation(msg.Location, msg.Chat.Id); err != nil {
+		return fmt.Errorf("error saving the watch location: %w", err)
+	}
+	lang := messageLanguage(msg)
+	return reply(ctx, msg, i18n.T(lang, "watch.saved", msg.Location.Latitude, msg.Location.Longitude), nil)
+}
+
+func privateChat(chat *model.Chat) bool {
+	return chat.Type == "private"
+}
@@ -0,0 +1,74 @@
+package country
+
+import (
+	"alerts/internal/geo"
+	_ "embed"
+	"encoding/csv"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// box is a latitude/longitude bounding box. Boxes never cross the
+// antimeridian; a country that does is split in two.
+type box struct {
+	minLatitude, minLongitude, maxLatitude, maxLongitude float64
+}
+
+// boundsCSV lists the countries too large or scattered for their centroid to
+// stand in for them, as one box per mainland or island group.
+//
+//go:embed bounds.csv
+var boundsCSV string
+
+var boxes = map[string][]box{}
+
+func init() {
+	records, err := csv.NewReader(strings.NewReader(boundsCSV)).ReadAll()
+	if err != nil {
+		log.Fatal("Failed to parse the embedded country bounds:", err)
+	}
+	for _, record := range records[1:] {
+		var b [4]float64
+		for i := range b {
+			b[i], _ = strconv.ParseFloat(record[i+1], 64)
+		}
+		boxes[record[0]] = append(boxes[record[0]], box{b[0], b[1], b[2], b[3]})
+	}
+}
+
+// Distance returns the distance in kilometres from a point to the nearest
+// edge of the country's bounding boxes, zero inside one. Countries without
+// boxes are small enough to be measured to their centroid.
+func (c *Country) Distance(latitude, longitude float64) float64 {
+	bs, ok := boxes[c.Code]
+	if !ok {
+		return geo.Distance(c.Latitude, c.Longitude, latitude, longitude)
+	}
+	nearest := math.Inf(1)
+	for _, b := range bs {
+		nearest = math.Min(nearest, b.distance(latitude, longitude))
+	}
+	return nearest
+}
+
+// distance measures to the nearest point of the box, taking the shorter way
+// round in longitude.
+func (b box) distance(latitude, longitude float64) float64 {
+	lat := math.Max(b.minLatitude, math.Min(b.maxLatitude, latitude))
+	lon := longitude
+	if longitude < b.minLongitude || longitude > b.maxLongitude {
+		lon = b.minLongitude
+		if lonGap(longitude, b.maxLongitude) < lonGap(longitude, b.minLongitude) {
+			lon = b.maxLongitude
+		}
+	}
+	return geo.Distance(lat, lon, latitude, longitude)
+}
+
+// lonGap is the angle between two longitudes, at most 180 degrees.
+func lonGap(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+	return math.Min(d, 360-d)
+}
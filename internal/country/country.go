@@ -0,0 +1,116 @@
+package country
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Global is the pseudo country code for "alerts from anywhere".
+const Global = "all"
+
+// landlocked lists the countries without a sea coast. The Caspian is treated
+// as a lake, so its shores don't count.
+var landlocked = map[string]bool{
+	"ad": true, "af": true, "am": true, "at": true, "az": true, "bf": true, "bi": true,
+	"bo": true, "bt": true, "bw": true, "by": true, "cf": true, "ch": true, "cz": true,
+	"et": true, "hu": true, "kg": true, "kz": true, "la": true, "li": true, "ls": true,
+	"lu": true, "md": true, "mk": true, "ml": true, "mn": true, "mw": true, "ne": true,
+	"np": true, "py": true, "rs": true, "rw": true, "sk": true, "sm": true, "ss": true,
+	"td": true, "tj": true, "tm": true, "ug": true, "uz": true, "va": true, "zm": true,
+	"zw": true,
+}
+
+// Country is an ISO 3166-1 entry. Code is the lower case alpha-2 code, which
+// is what Nominatim returns as country_code.
+type Country struct {
+	Code      string
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Aliases   []string
+}
+
+//go:embed countries.csv
+var countriesCSV string
+
+var (
+	countries []*Country
+	byCode    = map[string]*Country{}
+)
+
+func init() {
+	records, err := csv.NewReader(strings.NewReader(countriesCSV)).ReadAll()
+	if err != nil {
+		log.Fatal("Failed to parse the embedded country list:", err)
+	}
+	for _, record := range records[1:] {
+		latitude, _ := strconv.ParseFloat(record[2], 64)
+		longitude, _ := strconv.ParseFloat(record[3], 64)
+		c := &Country{Code: record[0], Name: record[1], Latitude: latitude, Longitude: longitude}
+		if record[4] != "" {
+			c.Aliases = strings.Split(record[4], "|")
+		}
+		countries = append(countries, c)
+		byCode[c.Code] = c
+	}
+	sort.Slice(countries, func(i, j int) bool { return normalize(countries[i].Name) < normalize(countries[j].Name) })
+}
+
+// All returns every country sorted by name.
+func All() []*Country {
+	return countries
+}
+
+func Lookup(code string) (*Country, bool) {
+	c, ok := byCode[strings.ToLower(code)]
+	return c, ok
+}
+
+// Initial is the upper case first letter of the name without accents, used
+// to label alphabetical pages.
+func (c *Country) Initial() string {
+	return strings.ToUpper(normalize(c.Name)[:1])
+}
+
+// Coastal reports whether the country has a sea coast.
+func (c *Country) Coastal() bool {
+	return !landlocked[c.Code]
+}
+
+// Flag returns the emoji flag built from the regional indicator symbols of the code.
+func (c *Country) Flag() string {
+	var flag strings.Builder
+	for _, r := range strings.ToUpper(c.Code) {
+		flag.WriteRune(0x1F1E6 + r - 'A')
+	}
+	return flag.String()
+}
+
+// Label is the display form, e.g. "Japan 🇯🇵".
+func (c *Country) Label() string {
+	return c.Name + " " + c.Flag()
+}
+
+// DisplayName returns the label for a stored country code, including Global.
+func DisplayName(code string) string {
+	if code == Global {
+		return "🌍 The World"
+	}
+	if c, ok := Lookup(code); ok {
+		return c.Label()
+	}
+	return code
+}
+
+// Valid reports whether code can be stored as a country preference.
+func Valid(code string) bool {
+	if code == Global {
+		return true
+	}
+	_, ok := byCode[code]
+	return ok
+}
@@ -0,0 +1,122 @@
+package country
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// accentFolder maps the accented letters used in country names and common
+// spellings to plain ASCII.
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// Find returns the country whose code, name or one of its aliases is query,
+// ignoring case and accents. Unlike Search it doesn't guess.
+func Find(query string) (*Country, bool) {
+	query = normalize(query)
+	for _, c := range countries {
+		if query == c.Code || query == normalize(c.Name) || slices.ContainsFunc(c.Aliases, func(alias string) bool { return normalize(alias) == query }) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Search returns up to limit countries matching query, best match first. It
+// matches codes, names and aliases exactly, by prefix, by substring and
+// finally by edit distance so small typos ("nepl", "chille") still work.
+func Search(query string, limit int) []*Country {
+	query = normalize(query)
+	if query == "" {
+		return nil
+	}
+	type match struct {
+		country *Country
+		score   int
+	}
+	matches := []match{}
+	for _, c := range countries {
+		best := -1
+		if query == c.Code {
+			best = 0
+		}
+		for _, candidate := range append([]string{c.Name}, c.Aliases...) {
+			if score := matchScore(query, normalize(candidate)); score >= 0 && (best < 0 || score < best) {
+				best = score
+			}
+		}
+		if best >= 0 {
+			matches = append(matches, match{country: c, score: best})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+
+	// Only fall back to typo matches when nothing matched literally, and then
+	// only keep the closest ones.
+	result := []*Country{}
+	for _, m := range matches {
+		if len(result) == limit || m.score > max(matches[0].score, 2) {
+			break
+		}
+		result = append(result, m.country)
+	}
+	return result
+}
+
+// matchScore returns how well query matches candidate, lower is better, or -1
+// for no match.
+func matchScore(query, candidate string) int {
+	switch {
+	case query == candidate:
+		return 0
+	case strings.HasPrefix(candidate, query):
+		return 1
+	case len(query) >= 3 && strings.Contains(candidate, query):
+		return 2
+	}
+	if len(query) < 4 {
+		return -1
+	}
+	// Compare against the candidate cut to the query's length as well, so a
+	// typo in the first word of a long name still matches.
+	distance := levenshtein(query, candidate)
+	if len(candidate) > len(query) {
+		distance = min(distance, levenshtein(query, candidate[:len(query)]))
+	}
+	if distance <= len(query)/4+1 {
+		return 3 + distance
+	}
+	return -1
+}
+
+func normalize(s string) string {
+	return accentFolder.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
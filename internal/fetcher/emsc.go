@@ -0,0 +1,111 @@
+package fetcher
+
+import (
+	"alerts/config"
+	"alerts/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// EMSCSource reads the EMSC seismic portal FDSN service with format=json,
+// which returns GeoJSON with EMSC specific properties.
+type EMSCSource struct{}
+
+// emscUpdated remembers the last update of every event in the previous EMSC
+// response, like featureUpdated for USGS, so unchanged events aren't handed
+// to the scheduler and geocoded again on every poll.
+var emscUpdated = map[string]int64{}
+
+func (s *EMSCSource) Name() string {
+	return "emsc"
+}
+
+func (s *EMSCSource) Fetch(ctx context.Context) ([]*model.Event, error) {
+	URL := config.BotConf.EMSCUrl
+	if URL == "" {
+		return nil, errors.New("emscURL is not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "earthquake-alert/1.0")
+	res, err := Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("emsc returned status %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	data := new(model.EMSCData)
+	if err = json.Unmarshal(body, data); err != nil {
+		return nil, err
+	}
+
+	events := []*model.Event{}
+	seen := make(map[string]int64, len(data.Features))
+	for _, feature := range data.Features {
+		if feature.Properties == nil {
+			continue
+		}
+		if feature.Properties.Magnitude < config.BotConf.Magnitude {
+			continue
+		}
+		event, err := emscEvent(feature)
+		if err != nil {
+			continue
+		}
+		seen[event.Id] = event.Updated
+		if updated, ok := emscUpdated[event.Id]; ok && updated >= event.Updated {
+			continue
+		}
+		events = append(events, event)
+	}
+	emscUpdated = seen
+	return events, nil
+}
+
+func emscEvent(feature *model.EMSCFeature) (*model.Event, error) {
+	p := feature.Properties
+	occurred, err := time.Parse(time.RFC3339Nano, p.Time)
+	if err != nil {
+		return nil, err
+	}
+	// Events without a last update time count as updated when they occurred.
+	updated := occurred
+	if p.LastUpdate != "" {
+		if updated, err = time.Parse(time.RFC3339Nano, p.LastUpdate); err != nil {
+			return nil, err
+		}
+	}
+	// The id is used as is, matching the EMSC ids of the FDSN text and
+	// QuakeML formats.
+	id := feature.Id
+	if id == "" {
+		id = p.SourceId
+	}
+	return &model.Event{
+		Id:        id,
+		Ids:       []string{id},
+		Source:    "emsc",
+		Title:     fmt.Sprintf("M %.1f - %s", p.Magnitude, p.FlynnRegion),
+		Place:     p.FlynnRegion,
+		Magnitude: p.Magnitude,
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+		Depth:     p.Depth,
+		Time:      occurred.UnixMilli(),
+		Updated:   updated.UnixMilli(),
+		MagType:   p.MagType,
+	}, nil
+}
@@ -0,0 +1,321 @@
+package fetcher
+
+import (
+	"alerts/config"
+	"alerts/model"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatGeoJSON = "geojson"
+	FormatText    = "text"
+	FormatQuakeML = "xml"
+)
+
+// FDSNClient queries an fdsnws-event service, e.g.
+// https://earthquake.usgs.gov/fdsnws/event/1/query.
+type FDSNClient struct {
+	BaseURL string
+	Format  string
+	Source  string
+}
+
+// FDSNCircle restricts a query to events within MaxRadius degrees of a point.
+type FDSNCircle struct {
+	Latitude  float64
+	Longitude float64
+	MaxRadius float64
+}
+
+// FDSNBox restricts a query to a latitude/longitude bounding box.
+type FDSNBox struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+type FDSNQuery struct {
+	StartTime    time.Time
+	EndTime      time.Time
+	MinMagnitude float64
+	Circle       *FDSNCircle
+	Box          *FDSNBox
+	Limit        int
+}
+
+func (c *FDSNClient) format() string {
+	if c.Format == "" {
+		return FormatGeoJSON
+	}
+	return c.Format
+}
+
+// URL builds the query URL for q.
+func (c *FDSNClient) URL(q *FDSNQuery) string {
+	params := url.Values{}
+	params.Set("format", c.format())
+	params.Set("orderby", "time")
+	if !q.StartTime.IsZero() {
+		params.Set("starttime", q.StartTime.UTC().Format("2006-01-02T15:04:05"))
+	}
+	if !q.EndTime.IsZero() {
+		params.Set("endtime", q.EndTime.UTC().Format("2006-01-02T15:04:05"))
+	}
+	if q.MinMagnitude > 0 {
+		params.Set("minmagnitude", strconv.FormatFloat(q.MinMagnitude, 'f', -1, 64))
+	}
+	if q.Circle != nil {
+		params.Set("latitude", strconv.FormatFloat(q.Circle.Latitude, 'f', -1, 64))
+		params.Set("longitude", strconv.FormatFloat(q.Circle.Longitude, 'f', -1, 64))
+		params.Set("maxradius", strconv.FormatFloat(q.Circle.MaxRadius, 'f', -1, 64))
+	}
+	if q.Box != nil {
+		params.Set("minlatitude", strconv.FormatFloat(q.Box.MinLatitude, 'f', -1, 64))
+		params.Set("maxlatitude", strconv.FormatFloat(q.Box.MaxLatitude, 'f', -1, 64))
+		params.Set("minlongitude", strconv.FormatFloat(q.Box.MinLongitude, 'f', -1, 64))
+		params.Set("maxlongitude", strconv.FormatFloat(q.Box.MaxLongitude, 'f', -1, 64))
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return c.BaseURL + "?" + params.Encode()
+}
+
+func (c *FDSNClient) Query(ctx context.Context, q *FDSNQuery) ([]*model.Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(q), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "earthquake-alert/1.0")
+	res, err := Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	// fdsnws-event answers 204 when nothing matches the query.
+	if res.StatusCode == http.StatusNoContent {
+		return []*model.Event{}, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fdsn service returned status %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return c.parse(body)
+}
+
+func (c *FDSNClient) parse(body []byte) ([]*model.Event, error) {
+	switch c.format() {
+	case FormatGeoJSON:
+		return parseFDSNGeoJSON(body, c.Source)
+	case FormatText:
+		return parseFDSNText(body, c.Source)
+	case FormatQuakeML:
+		return parseQuakeML(body, c.Source)
+	}
+	return nil, fmt.Errorf("unsupported fdsn format %q", c.Format)
+}
+
+func parseFDSNGeoJSON(body []byte, source string) ([]*model.Event, error) {
+	data := new(model.Data)
+	if err := json.Unmarshal(body, data); err != nil {
+		return nil, err
+	}
+	events := []*model.Event{}
+	for _, feature := range data.Features {
+		if feature.Geo == nil || len(feature.Geo.Coordinates) < 3 || feature.Properties == nil {
+			continue
+		}
+		events = append(events, geoJSONEvent(feature, source))
+	}
+	return events, nil
+}
+
+// parseFDSNText reads the pipe separated text format:
+// #EventID|Time|Latitude|Longitude|Depth/km|Author|Catalog|Contributor|ContributorID|MagType|Magnitude|MagAuthor|EventLocationName
+func parseFDSNText(body []byte, source string) ([]*model.Event, error) {
+	events := []*model.Event{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) < 13 {
+			return nil, fmt.Errorf("malformed fdsn text line %q", line)
+		}
+		occurred, err := parseFDSNTime(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		latitude, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, err
+		}
+		longitude, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, err
+		}
+		depth, _ := strconv.ParseFloat(fields[4], 64)
+		magnitude, _ := strconv.ParseFloat(fields[10], 64)
+		place := fields[12]
+		events = append(events, &model.Event{
+			Id:        fields[0],
+			Ids:       []string{fields[0]},
+			Source:    source,
+			Title:     fmt.Sprintf("M %.1f - %s", magnitude, place),
+			Place:     place,
+			Magnitude: magnitude,
+			Latitude:  latitude,
+			Longitude: longitude,
+			Depth:     depth,
+			Time:      occurred.UnixMilli(),
+			MagType:   fields[9],
+			Network:   fields[6],
+		})
+	}
+	return events, scanner.Err()
+}
+
+// parseQuakeML reads QuakeML 1.2, using each event's preferred origin and
+// magnitude, falling back to the first one listed.
+func parseQuakeML(body []byte, source string) ([]*model.Event, error) {
+	data := new(model.QuakeML)
+	if err := xml.Unmarshal(body, data); err != nil {
+		return nil, err
+	}
+	events := []*model.Event{}
+	if data.EventParameters == nil {
+		return events, nil
+	}
+	for _, e := range data.EventParameters.Events {
+		origin := preferredOrigin(e)
+		if origin == nil {
+			continue
+		}
+		occurred, err := parseFDSNTime(origin.Time.Value)
+		if err != nil {
+			return nil, err
+		}
+		var magnitude float64
+		var magType string
+		if mag := preferredMagnitude(e); mag != nil {
+			magnitude = mag.Mag.Value
+			magType = mag.Type
+		}
+		place := ""
+		for _, description := range e.Descriptions {
+			if description.Type == "" || description.Type == "region name" || description.Type == "earthquake name" {
+				place = description.Text
+				break
+			}
+		}
+		id := quakeMLEventId(e.PublicID)
+		events = append(events, &model.Event{
+			Id:        id,
+			Ids:       []string{id},
+			Source:    source,
+			Title:     fmt.Sprintf("M %.1f - %s", magnitude, place),
+			Place:     place,
+			Magnitude: magnitude,
+			Latitude:  origin.Latitude.Value,
+			Longitude: origin.Longitude.Value,
+			// QuakeML depths are in metres.
+			Depth:   origin.Depth.Value / 1000,
+			Time:    occurred.UnixMilli(),
+			MagType: magType,
+		})
+	}
+	return events, nil
+}
+
+func preferredOrigin(e *model.QuakeMLEvent) *model.QuakeMLOrigin {
+	for _, origin := range e.Origins {
+		if origin.PublicID == e.PreferredOriginID {
+			return origin
+		}
+	}
+	if len(e.Origins) > 0 {
+		return e.Origins[0]
+	}
+	return nil
+}
+
+func preferredMagnitude(e *model.QuakeMLEvent) *model.QuakeMLMagnitude {
+	for _, magnitude := range e.Magnitudes {
+		if magnitude.PublicID == e.PreferredMagnitudeID {
+			return magnitude
+		}
+	}
+	if len(e.Magnitudes) > 0 {
+		return e.Magnitudes[0]
+	}
+	return nil
+}
+
+// quakeMLEventId strips the resource prefix from a public id such as
+// "quakeml:us.anss.org/event/us7000abcd" so it matches the GeoJSON id. Ids
+// that are query URLs, like USGS's "...query?eventid=us7000abcd&format=quakeml",
+// give the eventid parameter.
+func quakeMLEventId(publicID string) string {
+	if _, query, ok := strings.Cut(publicID, "?"); ok {
+		for _, param := range strings.Split(query, "&") {
+			if key, value, _ := strings.Cut(param, "="); strings.EqualFold(key, "eventid") {
+				return value
+			}
+		}
+	}
+	if i := strings.LastIndexAny(publicID, "/="); i >= 0 {
+		return publicID[i+1:]
+	}
+	return publicID
+}
+
+func parseFDSNTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised fdsn time %q", value)
+}
+
+// FDSNSource polls an FDSN service for events from the last FDSNWindow minutes.
+type FDSNSource struct {
+	client *FDSNClient
+}
+
+func NewFDSNSource(baseURL, format string) *FDSNSource {
+	return &FDSNSource{client: &FDSNClient{BaseURL: baseURL, Format: format, Source: "fdsn"}}
+}
+
+func (s *FDSNSource) Name() string {
+	return "fdsn"
+}
+
+func (s *FDSNSource) Fetch(ctx context.Context) ([]*model.Event, error) {
+	if s.client.BaseURL == "" {
+		return nil, errors.New("fdsnURL is not configured")
+	}
+	return s.client.Query(ctx, &FDSNQuery{
+		StartTime:    time.Now().Add(-time.Duration(config.BotConf.FDSNWindow) * time.Minute),
+		MinMagnitude: config.BotConf.Magnitude,
+	})
+}
@@ -0,0 +1,310 @@
+package fetcher
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// The samples below follow the fdsnws-event responses of the agencies named
+// in each case, cut down to the fields the parsers read.
+
+const usgsText = `#EventID|Time|Latitude|Longitude|Depth/km|Author|Catalog|Contributor|ContributorID|MagType|Magnitude|MagAuthor|EventLocationName
+us7000m9g4|2024-04-02T23:58:11.740|23.819|121.562|34.75|us|us|us|us7000m9g4|mww|7.4|us|15 km S of Hualien City, Taiwan
+`
+
+const emscText = `#EventID|Time|Latitude|Longitude|Depth/km|Author|Catalog|Contributor|ContributorID|MagType|Magnitude|MagAuthor|EventLocationName
+20240101_0000078|2024-01-01T07:10:09.5Z|37.50|137.27|10.0|EMSC|EMSC-RTS|EMSC|1597578|mw|7.5|EMSC|NEAR WEST COAST OF HONSHU, JAPAN
+`
+
+const ingvText = `#EventID|Time|Latitude|Longitude|Depth/Km|Author|Catalog|Contributor|ContributorID|MagType|Magnitude|MagAuthor|EventLocationName|EventType
+37303911|2024-02-05T00:13:05.250000|43.0155|12.8667|6.5|SURVEY-INGV||||ML|2.1|--|3 km NE Muccia (MC)|earthquake
+`
+
+type wantEvent struct {
+	id        string
+	time      time.Time
+	latitude  float64
+	longitude float64
+	depth     float64
+	magnitude float64
+	magType   string
+	network   string
+	place     string
+}
+
+func TestParseFDSNText(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []wantEvent
+	}{
+		{
+			name: "usgs",
+			body: usgsText,
+			want: []wantEvent{{
+				id:        "us7000m9g4",
+				time:      time.Date(2024, 4, 2, 23, 58, 11, 740e6, time.UTC),
+				latitude:  23.819,
+				longitude: 121.562,
+				depth:     34.75,
+				magnitude: 7.4,
+				magType:   "mww",
+				network:   "us",
+				place:     "15 km S of Hualien City, Taiwan",
+			}},
+		},
+		{
+			name: "emsc",
+			body: emscText,
+			want: []wantEvent{{
+				id:        "20240101_0000078",
+				time:      time.Date(2024, 1, 1, 7, 10, 9, 500e6, time.UTC),
+				latitude:  37.50,
+				longitude: 137.27,
+				depth:     10,
+				magnitude: 7.5,
+				magType:   "mw",
+				network:   "EMSC-RTS",
+				place:     "NEAR WEST COAST OF HONSHU, JAPAN",
+			}},
+		},
+		{
+			name: "ingv with empty catalog columns and an extra one",
+			body: ingvText,
+			want: []wantEvent{{
+				id:        "37303911",
+				time:      time.Date(2024, 2, 5, 0, 13, 5, 250e6, time.UTC),
+				latitude:  43.0155,
+				longitude: 12.8667,
+				depth:     6.5,
+				magnitude: 2.1,
+				magType:   "ML",
+				network:   "",
+				place:     "3 km NE Muccia (MC)",
+			}},
+		},
+		{
+			name: "header only",
+			body: "#EventID|Time|Latitude|Longitude|Depth/km|Author|Catalog|Contributor|ContributorID|MagType|Magnitude|MagAuthor|EventLocationName\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := parseFDSNText([]byte(tt.body), "test")
+			if err != nil {
+				t.Fatalf("parseFDSNText: %v", err)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := events[i]
+				if got.Id != want.id || got.Place != want.place || got.MagType != want.magType || got.Network != want.network {
+					t.Errorf("got id %q place %q magType %q network %q, want %q %q %q %q",
+						got.Id, got.Place, got.MagType, got.Network, want.id, want.place, want.magType, want.network)
+				}
+				if got.Time != want.time.UnixMilli() {
+					t.Errorf("time = %s, want %s", time.UnixMilli(got.Time).UTC(), want.time)
+				}
+				if !near(got.Latitude, want.latitude) || !near(got.Longitude, want.longitude) {
+					t.Errorf("epicenter = %v, %v, want %v, %v", got.Latitude, got.Longitude, want.latitude, want.longitude)
+				}
+				if !near(got.Depth, want.depth) {
+					t.Errorf("depth = %v km, want %v km", got.Depth, want.depth)
+				}
+				if !near(got.Magnitude, want.magnitude) {
+					t.Errorf("magnitude = %v, want %v", got.Magnitude, want.magnitude)
+				}
+				if got.Source != "test" || len(got.Ids) != 1 || got.Ids[0] != want.id {
+					t.Errorf("source %q ids %v, want test [%s]", got.Source, got.Ids, want.id)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFDSNTextMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"too few columns", "us7000m9g4|2024-04-02T23:58:11.740|23.819|121.562\n"},
+		{"bad time", "us7000m9g4|yesterday|23.819|121.562|34.75|us|us|us|us7000m9g4|mww|7.4|us|Taiwan\n"},
+		{"bad latitude", "us7000m9g4|2024-04-02T23:58:11.740|north|121.562|34.75|us|us|us|us7000m9g4|mww|7.4|us|Taiwan\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseFDSNText([]byte(tt.body), "test"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+const usgsQuakeML = `<?xml version="1.0" encoding="UTF-8"?>
+<q:quakeml xmlns="http://quakeml.org/xmlns/bed/1.2" xmlns:anss="http://anss.org/xmlns/event/0.1" xmlns:catalog="http://anss.org/xmlns/catalog/0.1" xmlns:q="http://quakeml.org/xmlns/quakeml/1.2">
+<eventParameters publicID="quakeml:earthquake.usgs.gov/fdsnws/event/1/query">
+<event catalog:datasource="us" catalog:eventsource="us" catalog:eventid="7000m9g4" publicID="quakeml:earthquake.usgs.gov/fdsnws/event/1/query?eventid=us7000m9g4&amp;format=quakeml">
+<description><type>earthquake name</type><text>15 km S of Hualien City, Taiwan</text></description>
+<origin catalog:datasource="at" publicID="quakeml:earthquake.usgs.gov/product/origin/at00sbcpn0/at/1712102466098/product.xml">
+<time><value>2024-04-02T23:58:20.000Z</value></time>
+<longitude><value>121.67</value></longitude>
+<latitude><value>23.95</value></latitude>
+<depth><value>20000</value></depth>
+</origin>
+<origin catalog:datasource="us" publicID="quakeml:earthquake.usgs.gov/product/origin/us7000m9g4/us/1717531505040/product.xml">
+<time><value>2024-04-02T23:58:11.740Z</value></time>
+<longitude><value>121.5619</value></longitude>
+<latitude><value>23.819</value></latitude>
+<depth><value>34750</value><uncertainty>1692</uncertainty></depth>
+</origin>
+<magnitude catalog:datasource="at" publicID="quakeml:earthquake.usgs.gov/product/origin/at00sbcpn0/at/1712102466098/product.xml#magnitude">
+<mag><value>7.5</value></mag>
+<type>Mi</type>
+</magnitude>
+<magnitude catalog:datasource="us" publicID="quakeml:earthquake.usgs.gov/product/origin/us7000m9g4/us/1717531505040/product.xml#magnitude">
+<mag><value>7.4</value></mag>
+<type>mww</type>
+</magnitude>
+<preferredOriginID>quakeml:earthquake.usgs.gov/product/origin/us7000m9g4/us/1717531505040/product.xml</preferredOriginID>
+<preferredMagnitudeID>quakeml:earthquake.usgs.gov/product/origin/us7000m9g4/us/1717531505040/product.xml#magnitude</preferredMagnitudeID>
+<type>earthquake</type>
+</event>
+</eventParameters>
+</q:quakeml>`
+
+const emscQuakeML = `<?xml version="1.0" encoding="UTF-8"?>
+<q:quakeml xmlns="http://quakeml.org/xmlns/bed/1.2" xmlns:q="http://quakeml.org/xmlns/quakeml/1.2">
+<eventParameters publicID="smi:www.emsc-csem.org/ws/event/">
+<event publicID="smi:www.emsc-csem.org/event/20240101_0000078">
+<description><text>NEAR WEST COAST OF HONSHU, JAPAN</text><type>region name</type></description>
+<origin publicID="smi:www.emsc-csem.org/origin/1597578">
+<time><value>2024-01-01T07:10:09.5Z</value></time>
+<latitude><value>37.5</value></latitude>
+<longitude><value>137.27</value></longitude>
+<depth><value>10000</value></depth>
+</origin>
+<magnitude publicID="smi:www.emsc-csem.org/magnitude/1597578">
+<mag><value>7.5</value></mag>
+<type>mw</type>
+</magnitude>
+</event>
+<event publicID="smi:www.emsc-csem.org/event/20240101_0000099">
+<description><text>CENTRAL ITALY</text><type>region name</type></description>
+</event>
+</eventParameters>
+</q:quakeml>`
+
+const ingvQuakeML = `<?xml version="1.0" encoding="UTF-8"?>
+<q:quakeml xmlns="http://quakeml.org/xmlns/bed/1.2" xmlns:q="http://quakeml.org/xmlns/quakeml/1.2" xmlns:ingv="http://webservices.ingv.it/fdsnws/event/1">
+<eventParameters publicID="smi:webservices.ingv.it/fdsnws/event/1/query">
+<event publicID="smi:webservices.ingv.it/fdsnws/event/1/query?eventId=37303911">
+<type>earthquake</type>
+<description><type>region name</type><text>3 km NE Muccia (MC)</text></description>
+<preferredMagnitudeID>smi:webservices.ingv.it/fdsnws/event/1/query?magnitudeId=131573881</preferredMagnitudeID>
+<preferredOriginID>smi:webservices.ingv.it/fdsnws/event/1/query?originId=126413341</preferredOriginID>
+<origin publicID="smi:webservices.ingv.it/fdsnws/event/1/query?originId=126413341">
+<time><value>2024-02-05T00:13:05.250000</value></time>
+<latitude><value>43.0155</value></latitude>
+<longitude><value>12.8667</value></longitude>
+<depth><value>6500</value></depth>
+</origin>
+<magnitude publicID="smi:webservices.ingv.it/fdsnws/event/1/query?magnitudeId=131573881">
+<mag><value>2.1</value></mag>
+<type>ML</type>
+</magnitude>
+</event>
+</eventParameters>
+</q:quakeml>`
+
+func TestParseQuakeML(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []wantEvent
+	}{
+		{
+			name: "usgs prefers the listed origin and magnitude",
+			body: usgsQuakeML,
+			want: []wantEvent{{
+				id:        "us7000m9g4",
+				time:      time.Date(2024, 4, 2, 23, 58, 11, 740e6, time.UTC),
+				latitude:  23.819,
+				longitude: 121.5619,
+				depth:     34.75,
+				magnitude: 7.4,
+				magType:   "mww",
+				place:     "15 km S of Hualien City, Taiwan",
+			}},
+		},
+		{
+			name: "emsc falls back to the first origin and skips events without one",
+			body: emscQuakeML,
+			want: []wantEvent{{
+				id:        "20240101_0000078",
+				time:      time.Date(2024, 1, 1, 7, 10, 9, 500e6, time.UTC),
+				latitude:  37.5,
+				longitude: 137.27,
+				depth:     10,
+				magnitude: 7.5,
+				magType:   "mw",
+				place:     "NEAR WEST COAST OF HONSHU, JAPAN",
+			}},
+		},
+		{
+			name: "ingv",
+			body: ingvQuakeML,
+			want: []wantEvent{{
+				id:        "37303911",
+				time:      time.Date(2024, 2, 5, 0, 13, 5, 250e6, time.UTC),
+				latitude:  43.0155,
+				longitude: 12.8667,
+				depth:     6.5,
+				magnitude: 2.1,
+				magType:   "ML",
+				place:     "3 km NE Muccia (MC)",
+			}},
+		},
+		{
+			name: "no event parameters",
+			body: `<q:quakeml xmlns="http://quakeml.org/xmlns/bed/1.2" xmlns:q="http://quakeml.org/xmlns/quakeml/1.2"></q:quakeml>`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := parseQuakeML([]byte(tt.body), "test")
+			if err != nil {
+				t.Fatalf("parseQuakeML: %v", err)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := events[i]
+				if got.Id != want.id || got.Place != want.place || got.MagType != want.magType {
+					t.Errorf("got id %q place %q magType %q, want %q %q %q", got.Id, got.Place, got.MagType, want.id, want.place, want.magType)
+				}
+				if got.Time != want.time.UnixMilli() {
+					t.Errorf("time = %s, want %s", time.UnixMilli(got.Time).UTC(), want.time)
+				}
+				if !near(got.Latitude, want.latitude) || !near(got.Longitude, want.longitude) {
+					t.Errorf("epicenter = %v, %v, want %v, %v", got.Latitude, got.Longitude, want.latitude, want.longitude)
+				}
+				// QuakeML gives the depth in metres.
+				if !near(got.Depth, want.depth) {
+					t.Errorf("depth = %v km, want %v km", got.Depth, want.depth)
+				}
+				if !near(got.Magnitude, want.magnitude) {
+					t.Errorf("magnitude = %v, want %v", got.Magnitude, want.magnitude)
+				}
+			}
+		})
+	}
+}
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 1e-9
+}
@@ -2,9 +2,11 @@ package fetcher
 
 import (
 	"alerts/config"
+	"alerts/internal/bot"
+	"alerts/internal/telegram"
 	"alerts/model"
 	"alerts/repository"
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,153 +18,138 @@ import (
 var Client *http.Client = &http.Client{
 	Timeout: 10 * time.Second,
 }
-var ChatClient *http.Client = &http.Client{
-	Timeout: 5 * time.Second,
-}
 var update_id int64 = -1
 
-var countryNameMap = map[string]string{
-	"gb":  "Great Britain 🇬🇧",
-	"us":  "United States 🇺🇸",
-	"in":  "India 🇮🇳",
-	"ir":  "Iran 🇮🇷",
-	"jp":  "Japan 🇯🇵",
-	"it":  "Italy 🇮🇹",
-	"id":  "Indonesia 🇮🇩",
-	"ru":  "Russia 🇷🇺",
-	"all": "🌍 The World",
-}
+// Conditional GET state for the USGS feed. featureUpdated remembers the
+// updated time of every feature in the last feed so unchanged ones can be dropped.
+var feedETag string
+var feedLastModified string
+var feedGenerated int64
+var featureUpdated = map[string]int64{}
 
-func FetchEarthQuake() *model.Data {
+// FetchEarthQuake returns the features of the USGS feed that are new or have
+// been updated since the previous call. An unchanged feed yields no features.
+func FetchEarthQuake(ctx context.Context) (*model.Data, error) {
 
 	URL := config.BotConf.USGSUrl
-	req, err := http.NewRequest(http.MethodGet, URL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, URL, nil)
 	if err != nil {
 		log.Println("request creation failed")
-		panic("request creation failed")
-		return nil
+		return nil, err
+	}
+	if feedETag != "" {
+		req.Header.Set("If-None-Match", feedETag)
+	}
+	if feedLastModified != "" {
+		req.Header.Set("If-Modified-Since", feedLastModified)
 	}
 	res, err := Client.Do(req)
 	if err != nil {
 		log.Println("error fetching the response")
-		panic("error fetching the response")
-		return nil
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified {
+		log.Println("USGS feed not modified")
+		return new(model.Data), nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("usgs feed returned status %d", res.StatusCode)
 	}
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
 		log.Println("error reading the body")
-		panic("error reading the body")
-		return nil
+		return nil, err
 	}
 	data := new(model.Data)
 	err = json.Unmarshal(body, data)
 	if err != nil {
 		log.Println("error unmarshalling the response")
-		panic("error unmarshalling the response")
-		return nil
+		return nil, err
+	}
+	feedETag = res.Header.Get("ETag")
+	feedLastModified = res.Header.Get("Last-Modified")
+	if data.Metadata != nil {
+		if data.Metadata.Generated != 0 && data.Metadata.Generated == feedGenerated {
+			log.Println("USGS feed generated time unchanged")
+			return new(model.Data), nil
+		}
+		feedGenerated = data.Metadata.Generated
 	}
-	return data
+	return changedFeatures(data), nil
 }
 
-func FetchChatId() *model.ChatUsers {
+// ResetFeedState forgets the conditional GET state and the events already
+// seen so the next fetch from USGS and EMSC returns the whole feed.
+func ResetFeedState() {
+	feedETag = ""
+	feedLastModified = ""
+	feedGenerated = 0
+	featureUpdated = map[string]int64{}
+	emscUpdated = map[string]int64{}
+}
 
-	var URL = fmt.Sprintf("%s%s/getUpdates", config.BotConf.TelegramDomain, config.BotConf.BotToken)
-	if update_id != -1 {
-		URL = fmt.Sprintf("%s%s/getUpdates?offset=%d", config.BotConf.TelegramDomain, config.BotConf.BotToken, update_id+1)
-	}
-	req, err := http.NewRequest(http.MethodGet, URL, nil)
-	if err != nil {
-		log.Println("request creation failed")
-		panic("request creation failed")
-		return nil
-	}
-	res, err := ChatClient.Do(req)
-	if err != nil {
-		log.Println("error fetching the response")
-		return nil
+func changedFeatures(data *model.Data) *model.Data {
+	changed := new(model.Data)
+	changed.Metadata = data.Metadata
+	seen := make(map[string]int64, len(data.Features))
+	for _, feature := range data.Features {
+		if feature.Properties == nil {
+			continue
+		}
+		seen[feature.Id] = feature.Properties.Updated
+		if updated, ok := featureUpdated[feature.Id]; ok && updated >= feature.Properties.Updated {
+			continue
+		}
+		changed.Features = append(changed.Features, feature)
 	}
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Println("error reading the body")
-		panic("error reading the body")
-		return nil
+	featureUpdated = seen
+	return changed
+}
+
+// FetchChatId reads pending updates from Telegram and stores new users and
+// country selections. The offset only moves past an update once its user is
+// stored, so a database failure is retried on the next call; a message or
+// callback whose handler fails is logged and skipped.
+func FetchChatId(ctx context.Context) (*model.ChatUsers, error) {
+
+	params := &telegram.GetUpdatesParams{}
+	if update_id != -1 {
+		params.Offset = update_id + 1
 	}
-	data := new(model.ChatUsers)
-	err = json.Unmarshal(body, data)
+	results, err := telegram.Bot.GetUpdates(ctx, params)
 	if err != nil {
-		log.Println("error unmarshalling the response")
-		panic("error unmarshalling the response")
-		return nil
+		log.Println("error fetching the updates")
+		return nil, err
 	}
+	data := &model.ChatUsers{Results: results}
 	size := len(data.Results)
 	for i := range size {
-		if update_id < data.Results[i].UpdateId {
-			update_id = data.Results[i].UpdateId
-		}
 		if data.Results[i].Msg != nil {
 			user := new(model.InsertBotUser)
 			user.UserName = data.Results[i].Msg.Chat.UserName
 			user.ChatId = data.Results[i].Msg.Chat.Id
+			if data.Results[i].Msg.From != nil {
+				user.LanguageCode = data.Results[i].Msg.From.LanguageCode
+			}
 			if err := repository.InsertIntoTelegramBot(user); err != nil {
 				log.Println("error inserting the database")
-				panic(fmt.Sprintf("error inserting the record into database %s", err.Error()))
+				return data, fmt.Errorf("error inserting the record into database: %w", err)
+			}
+			if err := bot.HandleMessage(ctx, data.Results[i].Msg); err != nil {
+				log.Println("error handling the message:", err)
 			}
 		} else if data.Results[i].CallbackQuery != nil {
-			if err = repository.UpdateCountryPreference(data.Results[i].CallbackQuery.Data, data.Results[i].CallbackQuery.From.Id); err != nil {
-				log.Println("error updating the database")
-				panic(fmt.Sprintf("error updating the country preference %s", err.Error()))
-			} else {
-				selectedCountrymessage := fmt.Sprintf("You will now get EarthQuake notification for: %s", countryNameMap[data.Results[i].CallbackQuery.Data])
-				SendMessageToTelegram(data.Results[i].CallbackQuery.From.Id, selectedCountrymessage)
+			// Like a message, a callback that fails is only logged, so the
+			// offset still moves past it and it can't hold up later updates.
+			if err := bot.HandleCallbackQuery(ctx, data.Results[i].CallbackQuery); err != nil {
+				log.Println("error handling the callback query:", err)
 			}
-
+		}
+		if update_id < data.Results[i].UpdateId {
+			update_id = data.Results[i].UpdateId
 		}
 
 	}
-	return data
-}
-
-func SendMessageToTelegram(chatId int64, message string) error {
-	botToken := config.BotConf.BotToken
-	telegramAPI := fmt.Sprintf("%s%s/sendMessage", config.BotConf.TelegramDomain, botToken)
-	telegramMessage := &model.TelegramMessage{
-		ChatID: chatId,
-		Text:   message,
-	}
-
-	body, err := json.Marshal(telegramMessage)
-	if err != nil {
-		log.Println("failed to marshal message:", err)
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, telegramAPI, bytes.NewBuffer(body))
-	if err != nil {
-		log.Println("error creating Telegram request:", err)
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "earthquake-alert-bot/1.0")
-
-	client := http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Println("error sending request to Telegram:", err)
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("error reading response from Telegram:", err)
-		return err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Telegram API returned status %d: %s", resp.StatusCode, string(respBody))
-		return fmt.Errorf("telegram API error: %s", respBody)
-	}
-
-	log.Println("Telegram message sent successfully:", string(respBody))
-	return nil
+	return data, nil
 }
@@ -0,0 +1,57 @@
+package fetcher
+
+import (
+	"alerts/config"
+	"alerts/model"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Source is a provider of earthquake events. Every implementation normalizes
+// its feed into model.Event so the scheduler doesn't care where an event came from.
+type Source interface {
+	Name() string
+	Fetch(ctx context.Context) ([]*model.Event, error)
+}
+
+// NewSources builds the sources listed in config, skipping unknown names.
+func NewSources(names []string) []Source {
+	sources := []Source{}
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "usgs":
+			sources = append(sources, &USGSSource{})
+		case "emsc":
+			sources = append(sources, &EMSCSource{})
+		case "fdsn":
+			sources = append(sources, NewFDSNSource(config.BotConf.FDSNUrl, config.BotConf.FDSNFormat))
+		default:
+			log.Println("unknown earthquake source, skipping:", name)
+		}
+	}
+	return sources
+}
+
+// FetchEvents polls every source and merges the results. A failing source is
+// logged and skipped so one provider being down doesn't stop alerts from the
+// others; an error is only returned when every source failed.
+func FetchEvents(ctx context.Context, sources []Source) ([]*model.Event, error) {
+	events := []*model.Event{}
+	errs := []error{}
+	for _, source := range sources {
+		fetched, err := source.Fetch(ctx)
+		if err != nil {
+			log.Println("error fetching events from", source.Name(), err.Error())
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+			continue
+		}
+		events = append(events, fetched...)
+	}
+	if len(sources) > 0 && len(errs) == len(sources) {
+		return events, errors.Join(errs...)
+	}
+	return events, nil
+}
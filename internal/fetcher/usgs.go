@@ -0,0 +1,69 @@
+package fetcher
+
+import (
+	"alerts/model"
+	"context"
+	"strings"
+)
+
+type USGSSource struct{}
+
+func (s *USGSSource) Name() string {
+	return "usgs"
+}
+
+func (s *USGSSource) Fetch(ctx context.Context) ([]*model.Event, error) {
+	data, err := FetchEarthQuake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events := []*model.Event{}
+	for _, feature := range data.Features {
+		if feature.Geo == nil || len(feature.Geo.Coordinates) < 3 || feature.Properties == nil {
+			continue
+		}
+		events = append(events, geoJSONEvent(feature, s.Name()))
+	}
+	return events, nil
+}
+
+// geoJSONEvent converts a feature in the USGS GeoJSON layout, which is also
+// what USGS style FDSN services return for format=geojson.
+func geoJSONEvent(feature *model.Feature, source string) *model.Event {
+	return &model.Event{
+		Id:        feature.Id,
+		Source:    source,
+		Title:     feature.Properties.Title,
+		Place:     feature.Properties.Place,
+		Magnitude: feature.Properties.Magnitude,
+		Longitude: feature.Geo.Coordinates[0],
+		Latitude:  feature.Geo.Coordinates[1],
+		Depth:     feature.Geo.Coordinates[2],
+		Tsunami:   feature.Properties.Tsunami,
+		Time:      feature.Properties.Time,
+		Updated:   feature.Properties.Updated,
+		Ids:       splitIds(feature.Id, feature.Properties.Ids),
+
+		Alert:        feature.Properties.Alert,
+		MMI:          feature.Properties.MMI,
+		CDI:          feature.Properties.CDI,
+		Felt:         feature.Properties.Felt,
+		Significance: feature.Properties.Significance,
+		Status:       feature.Properties.Status,
+		MagType:      feature.Properties.MagType,
+		Network:      feature.Properties.Net,
+		URL:          feature.Properties.URL,
+	}
+}
+
+// splitIds turns the USGS ",us7000abcd,at00xyz," list into a slice that
+// always contains the preferred id.
+func splitIds(preferred, ids string) []string {
+	result := []string{preferred}
+	for _, id := range strings.Split(ids, ",") {
+		if id != "" && id != preferred {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+// Package filter decides which events match a subscriber's country, regions
+// and alert filters. It is shared by the alert loop and the query commands.
+package filter
+
+import (
+	"alerts/internal/country"
+	"alerts/internal/geo"
+	"alerts/internal/region"
+	"alerts/internal/seismic"
+	"alerts/model"
+	"alerts/repository"
+	"log"
+	"slices"
+)
+
+// pagerLevels are the USGS PAGER alert levels from lowest to highest.
+var pagerLevels = []string{"green", "yellow", "orange", "red"}
+
+// UserRegions loads the regions a user subscribed to, skipping stored
+// references that no longer parse.
+func UserRegions(chatId int64) ([]*region.Region, error) {
+	refs, err := repository.GetUserRegions(chatId)
+	if err != nil {
+		return nil, err
+	}
+	regions := []*region.Region{}
+	for _, ref := range refs {
+		r, err := region.Parse(ref)
+		if err != nil {
+			log.Println("Skipping region subscription:", err)
+			continue
+		}
+		regions = append(regions, r)
+	}
+	return regions, nil
+}
+
+// Covers reports whether a user's country or regions include the event,
+// whose epicenter lies in eventCountry.
+func Covers(countryCode string, regions []*region.Region, event *model.Event, eventCountry string) bool {
+	return (countryCode != "" && (countryCode == eventCountry || countryCode == country.Global)) || InRegions(regions, event)
+}
+
+// InRegions matches on the event coordinates, so it works offshore where
+// Nominatim returns no country code.
+func InRegions(regions []*region.Region, event *model.Event) bool {
+	for _, r := range regions {
+		if r.Contains(event.Latitude, event.Longitude) {
+			return true
+		}
+	}
+	return false
+}
+
+// Passes applies the user's optional filters on top of the magnitude one.
+// An event without a PAGER level fails a PAGER filter, and one that hasn't
+// been reviewed yet fails the review filter until an update marks it
+// reviewed. Only USGS reports either, so both filters limit alerts to USGS
+// events; the filter messages tell the user so.
+func Passes(preferences *model.UserPreferences, event *model.Event) bool {
+	if preferences.MinAlert != "" && slices.Index(pagerLevels, event.Alert) < slices.Index(pagerLevels, preferences.MinAlert) {
+		return false
+	}
+	if preferences.ReviewedOnly && event.Status != "reviewed" {
+		return false
+	}
+	if preferences.MaxDepth > 0 && event.Depth > preferences.MaxDepth {
+		return false
+	}
+	// The intensity filter needs a watch location to estimate shaking at. It
+	// compares the whole degree alerts show, so a quake shown as III passes
+	// a III+ filter.
+	if watch := preferences.Watch; preferences.MinIntensity > 0 && watch != nil {
+		distance := geo.Distance(watch.Latitude, watch.Longitude, event.Latitude, event.Longitude)
+		if float64(seismic.Level(seismic.EstimateMMI(event.Magnitude, event.Depth, distance))) < preferences.MinIntensity {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package geo
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+// Distance returns the great-circle distance in kilometres between two points.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// Destination returns the point distanceKm from the start along the initial
+// bearing, in degrees clockwise from north.
+func Destination(lat, lon, bearing, distanceKm float64) (float64, float64) {
+	phi1 := lat * math.Pi / 180
+	lambda1 := lon * math.Pi / 180
+	theta := bearing * math.Pi / 180
+	delta := distanceKm / earthRadiusKm
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1), math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2))
+	return phi2 * 180 / math.Pi, lambda2 * 180 / math.Pi
+}
@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check tracks the outcome of one recurring job, such as a polling loop.
+type Check struct {
+	mu                  sync.Mutex
+	name                string
+	consecutiveFailures int
+	lastSuccess         time.Time
+	lastError           string
+}
+
+type Status struct {
+	Name                string    `json:"name"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+var (
+	mu     sync.Mutex
+	checks = map[string]*Check{}
+	// FailureThreshold is the number of consecutive failures after which a
+	// check reports unhealthy.
+	FailureThreshold = 5
+)
+
+// Register returns the check with the given name, creating it if needed.
+func Register(name string) *Check {
+	mu.Lock()
+	defer mu.Unlock()
+	if check, ok := checks[name]; ok {
+		return check
+	}
+	check := &Check{name: name}
+	checks[name] = check
+	return check
+}
+
+func (c *Check) Success() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecutiveFailures = 0
+	c.lastSuccess = time.Now()
+	c.lastError = ""
+}
+
+// Failure records a failed run and returns the consecutive failure count.
+func (c *Check) Failure(err error) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecutiveFailures++
+	c.lastError = err.Error()
+	return c.consecutiveFailures
+}
+
+func (c *Check) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Status{
+		Name:                c.name,
+		Healthy:             c.consecutiveFailures < FailureThreshold,
+		ConsecutiveFailures: c.consecutiveFailures,
+		LastSuccess:         c.lastSuccess,
+		LastError:           c.lastError,
+	}
+}
+
+// Statuses returns the status of every registered check, sorted by name.
+func Statuses() []Status {
+	mu.Lock()
+	defer mu.Unlock()
+	statuses := []Status{}
+	for _, check := range checks {
+		statuses = append(statuses, check.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Handler reports every check as JSON, with 503 if any of them is unhealthy.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	statuses := Statuses()
+	code := http.StatusOK
+	for _, status := range statuses {
+		if !status.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		log.Println("error writing health response:", err)
+	}
+}
+
+// Serve exposes Handler on addr at /healthz until ctx is cancelled.
+func Serve(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", Handler)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Println("health server stopped:", err)
+		}
+	}()
+	context.AfterFunc(ctx, func() {
+		server.Close()
+	})
+}
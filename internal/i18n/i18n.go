@@ -0,0 +1,137 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+)
+
+const Default = "en"
+
+type Language struct {
+	Code string
+	Name string
+}
+
+// Languages lists the supported languages in the order offered to users.
+var Languages = []Language{
+	{Code: "en", Name: "English"},
+	{Code: "es", Name: "Español"},
+	{Code: "ja", Name: "日本語"},
+	{Code: "id", Name: "Bahasa Indonesia"},
+	{Code: "hi", Name: "हिन्दी"},
+}
+
+// message is either a plain string or a set of plural forms in the catalog.
+type message struct {
+	One   string `json:"one"`
+	Other string `json:"other"`
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &m.Other)
+	}
+	type forms message
+	return json.Unmarshal(data, (*forms)(m))
+}
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var catalogs = map[string]map[string]message{}
+
+func init() {
+	for _, language := range Languages {
+		data, err := locales.ReadFile(path.Join("locales", language.Code+".json"))
+		if err != nil {
+			log.Fatal("Missing message catalog:", err)
+		}
+		catalog := map[string]message{}
+		if err = json.Unmarshal(data, &catalog); err != nil {
+			log.Fatal("Invalid message catalog ", language.Code, ": ", err)
+		}
+		catalogs[language.Code] = catalog
+	}
+}
+
+// Resolve maps a Telegram language_code such as "es-419" to a supported
+// language, falling back to English.
+func Resolve(code string) string {
+	code = strings.ToLower(code)
+	code, _, _ = strings.Cut(code, "-")
+	if _, ok := catalogs[code]; ok {
+		return code
+	}
+	return Default
+}
+
+func Supported(code string) bool {
+	_, ok := catalogs[code]
+	return ok
+}
+
+// T returns the message for key in lang, formatted with args. Missing keys
+// fall back to English and then to the key itself.
+func T(lang, key string, args ...any) string {
+	return format(lookup(lang, key).Other, key, args)
+}
+
+// N is T for messages with plural forms, choosing the form for n. n is passed
+// to the format as the first argument.
+func N(lang, key string, n int, args ...any) string {
+	m := lookup(lang, key)
+	text := m.Other
+	if pluralOne(Resolve(lang), n) && m.One != "" {
+		text = m.One
+	}
+	return format(text, key, append([]any{n}, args...))
+}
+
+// Date formats t with the layout stored under key in lang. Layouts use Go's
+// reference time and write the month out in full ("January"), which is then
+// replaced with the language's name from "date.months".
+func Date(lang, key string, t time.Time) string {
+	text := t.Format(lookup(lang, key).Other)
+	if months := strings.Split(lookup(lang, "date.months").Other, "|"); len(months) == 12 {
+		text = strings.Replace(text, t.Month().String(), months[t.Month()-1], 1)
+	}
+	return text
+}
+
+func lookup(lang, key string) message {
+	if m, ok := catalogs[Resolve(lang)][key]; ok {
+		return m
+	}
+	if m, ok := catalogs[Default][key]; ok {
+		return m
+	}
+	return message{Other: key}
+}
+
+func format(text, key string, args []any) string {
+	if len(args) == 0 {
+		return text
+	}
+	if !strings.Contains(text, "%") {
+		log.Println("message has no verbs for its arguments:", key)
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// pluralOne implements the CLDR "one" category for the supported languages.
+// Japanese and Indonesian don't inflect for number.
+func pluralOne(lang string, n int) bool {
+	switch lang {
+	case "ja", "id":
+		return false
+	case "hi":
+		return n == 0 || n == 1
+	}
+	return n == 1
+}
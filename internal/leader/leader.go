@@ -0,0 +1,104 @@
+package leader
+
+import (
+	"alerts/config"
+	"alerts/repository"
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var isLeader atomic.Bool
+
+// IsLeader reports whether this replica currently holds the leader lock.
+// Only the leader polls Telegram, fetches feeds and runs cron jobs.
+func IsLeader() bool {
+	return isLeader.Load()
+}
+
+func setLeader(leader bool) {
+	if isLeader.Swap(leader) != leader {
+		if leader {
+			log.Println("Acquired leadership")
+		} else {
+			log.Println("Lost leadership, standing by")
+		}
+	}
+}
+
+// Elect campaigns for leadership using a Postgres advisory lock held on a
+// dedicated connection. If the leader dies its session ends, the lock is
+// released, and a follower picks it up on its next attempt.
+func Elect(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	key := config.BotConf.LeaderLockKey
+	interval := time.Duration(config.BotConf.LeaderInterval) * time.Second
+	var conn *sql.Conn
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			resign(conn, key)
+			return
+		case <-timer.C:
+			conn = campaign(ctx, conn, key)
+			timer.Reset(interval)
+		}
+	}
+}
+
+// campaign checks a held lock is still alive, or tries to take it, and returns
+// the connection to keep for the next round.
+func campaign(ctx context.Context, conn *sql.Conn, key int64) *sql.Conn {
+	if repository.DB == nil {
+		return nil
+	}
+	if conn == nil {
+		c, err := repository.DB.Conn(ctx)
+		if err != nil {
+			log.Println("Error opening leader election connection:", err)
+			setLeader(false)
+			return nil
+		}
+		conn = c
+	}
+	if IsLeader() {
+		if err := conn.PingContext(ctx); err != nil {
+			log.Println("Leader election connection failed:", err)
+			setLeader(false)
+			repository.DiscardConn(conn)
+			return nil
+		}
+		return conn
+	}
+	acquired, err := repository.TryAdvisoryLock(ctx, conn, key)
+	if err != nil {
+		// The lock may have been taken before the error.
+		repository.DiscardConn(conn)
+		return nil
+	}
+	setLeader(acquired)
+	return conn
+}
+
+func resign(conn *sql.Conn, key int64) {
+	if conn == nil {
+		return
+	}
+	if IsLeader() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := repository.AdvisoryUnlock(ctx, conn, key)
+		setLeader(false)
+		if err != nil {
+			// A pooled connection would keep holding the lock.
+			repository.DiscardConn(conn)
+			return
+		}
+	}
+	conn.Close()
+}
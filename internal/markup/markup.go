@@ -0,0 +1,171 @@
+// Package markup builds message text for Telegram's MarkdownV2 and HTML parse
+// modes, escaping every piece of untrusted text for the mode it is sent in.
+package markup
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode is a Telegram parse_mode value.
+type Mode string
+
+const (
+	MarkdownV2 Mode = "MarkdownV2"
+	HTML       Mode = "HTML"
+)
+
+// ParseMode maps a configured parse mode, case insensitively, to a Mode. An
+// unknown value returns MarkdownV2 together with an error.
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "markdownv2":
+		return MarkdownV2, nil
+	case "html":
+		return HTML, nil
+	}
+	return MarkdownV2, fmt.Errorf("unsupported parse mode %q", name)
+}
+
+// Every character that is significant in MarkdownV2 text, including the
+// backslash itself.
+var markdownV2Text = strings.NewReplacer(
+	"\\", "\\\\",
+	"_", "\\_",
+	"*", "\\*",
+	"[", "\\[",
+	"]", "\\]",
+	"(", "\\(",
+	")", "\\)",
+	"~", "\\~",
+	"`", "\\`",
+	">", "\\>",
+	"#", "\\#",
+	"+", "\\+",
+	"-", "\\-",
+	"=", "\\=",
+	"|", "\\|",
+	"{", "\\{",
+	"}", "\\}",
+	".", "\\.",
+	"!", "\\!",
+)
+
+// Inside the (...) part of an inline link only ) and \ must be escaped.
+var markdownV2URL = strings.NewReplacer("\\", "\\\\", ")", "\\)")
+
+// Inside pre and code entities only ` and \ must be escaped.
+var markdownV2Code = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+var htmlText = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var htmlAttribute = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// Escape makes text safe to use as plain text in m.
+func (m Mode) Escape(text string) string {
+	if m == HTML {
+		return htmlText.Replace(text)
+	}
+	return markdownV2Text.Replace(text)
+}
+
+func (m Mode) Bold(text string) string {
+	if m == HTML {
+		return "<b>" + htmlText.Replace(text) + "</b>"
+	}
+	return "*" + markdownV2Text.Replace(text) + "*"
+}
+
+func (m Mode) Italic(text string) string {
+	if m == HTML {
+		return "<i>" + htmlText.Replace(text) + "</i>"
+	}
+	return "_" + markdownV2Text.Replace(text) + "_"
+}
+
+// Link renders an inline link. An empty url renders just the text.
+func (m Mode) Link(text, url string) string {
+	if url == "" {
+		return m.Escape(text)
+	}
+	if m == HTML {
+		return `<a href="` + htmlAttribute.Replace(url) + `">` + htmlText.Replace(text) + "</a>"
+	}
+	return "[" + markdownV2Text.Replace(text) + "](" + markdownV2URL.Replace(url) + ")"
+}
+
+// Code renders text as an inline code span.
+func (m Mode) Code(text string) string {
+	if m == HTML {
+		return "<code>" + htmlText.Replace(text) + "</code>"
+	}
+	return "`" + markdownV2Code.Replace(text) + "`"
+}
+
+// Builder assembles a message piece by piece. Text passed to its methods is
+// escaped; Raw is for text that is already formatted for the builder's mode.
+type Builder struct {
+	mode Mode
+	text strings.Builder
+}
+
+func NewBuilder(mode Mode) *Builder {
+	return &Builder{mode: mode}
+}
+
+func (b *Builder) Mode() Mode {
+	return b.mode
+}
+
+func (b *Builder) Text(text string) *Builder {
+	b.text.WriteString(b.mode.Escape(text))
+	return b
+}
+
+// Textf escapes the formatted result, not just the arguments.
+func (b *Builder) Textf(format string, args ...any) *Builder {
+	return b.Text(fmt.Sprintf(format, args...))
+}
+
+func (b *Builder) Bold(text string) *Builder {
+	b.text.WriteString(b.mode.Bold(text))
+	return b
+}
+
+func (b *Builder) Italic(text string) *Builder {
+	b.text.WriteString(b.mode.Italic(text))
+	return b
+}
+
+func (b *Builder) Link(text, url string) *Builder {
+	b.text.WriteString(b.mode.Link(text, url))
+	return b
+}
+
+func (b *Builder) Code(text string) *Builder {
+	b.text.WriteString(b.mode.Code(text))
+	return b
+}
+
+func (b *Builder) Raw(text string) *Builder {
+	b.text.WriteString(text)
+	return b
+}
+
+func (b *Builder) Line() *Builder {
+	b.text.WriteByte('\n')
+	return b
+}
+
+func (b *Builder) Len() int {
+	return b.text.Len()
+}
+
+func (b *Builder) String() string {
+	return b.text.String()
+}
+
+// Messages returns the text split into parts Telegram will accept.
+func (b *Builder) Messages() []string {
+	return Split(b.String(), b.mode)
+}
@@ -0,0 +1,187 @@
+package markup
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// MaxLength is the most UTF-16 code units Telegram accepts in one message.
+	MaxLength = 4096
+	// MaxCaptionLength is the limit for photo and other media captions.
+	MaxCaptionLength = 1024
+)
+
+// Split cuts text into parts of at most MaxLength, measured on the formatted
+// text so the parsed result is never longer. Parts end at line breaks where
+// possible; a single line that is too long is cut between characters, never
+// inside an escape sequence, tag, HTML entity or link, and bold, italic and
+// the like are closed at the cut and reopened in the next part.
+func Split(text string, mode Mode) []string {
+	return split(text, mode, MaxLength)
+}
+
+func split(text string, mode Mode, limit int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	parts := []string{}
+	var current strings.Builder
+	size := 0
+	flush := func() {
+		if part := strings.TrimSpace(current.String()); part != "" {
+			parts = append(parts, part)
+		}
+		current.Reset()
+		size = 0
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		length := Length(line)
+		if size+length > limit {
+			flush()
+		}
+		for length > limit {
+			head, tail := cutLine(line, mode, limit)
+			parts = append(parts, head)
+			line = tail
+			length = Length(line)
+		}
+		current.WriteString(line)
+		size += length
+	}
+	flush()
+	return parts
+}
+
+// cutLine splits line at the last safe point within limit. Formatting spans
+// still open there are closed at the end of the head and reopened at the
+// start of the tail, so each part parses on its own.
+func cutLine(line string, mode Mode, limit int) (string, string) {
+	size, cut, safe := 0, 0, 0
+	var open, safeOpen []string
+	inTag, inEntity, inLink, escaped, double := false, false, false, false, false
+	// empty is set while the innermost span has no text yet; cutting there
+	// would leave an empty span behind.
+	empty := false
+	tagStart := 0
+	for i, r := range line {
+		size += utf16.RuneLen(r)
+		if size > limit {
+			break
+		}
+		cut = i + utf8.RuneLen(r)
+		depth, content := len(open), true
+		switch mode {
+		case HTML:
+			switch {
+			case r == '<':
+				inTag, tagStart = true, i
+			case r == '>' && inTag:
+				inTag, content = false, false
+				open = htmlSpan(open, line[tagStart:cut])
+			case r == '&':
+				inEntity = true
+			case r == ';':
+				inEntity = false
+			}
+			if inTag {
+				content = false
+			}
+		default:
+			switch {
+			case double:
+				// The second character of a __ or || marker.
+				double, content = false, false
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case len(open) > 0 && open[len(open)-1] == "`":
+				if r == '`' {
+					open, content = open[:len(open)-1], false
+				}
+			case r == '[':
+				inLink = true
+			case r == ')' && inLink:
+				inLink = false
+			case strings.ContainsRune("*_~|`", r):
+				marker := string(r)
+				if (r == '_' || r == '|') && strings.HasPrefix(line[cut:], marker) {
+					marker += marker
+					double = true
+				}
+				open, content = markdownSpan(open, marker), false
+			}
+		}
+		switch {
+		case content:
+			empty = false
+		case len(open) != depth:
+			empty = len(open) > depth
+		}
+		if inTag || inEntity || escaped || inLink || double || empty {
+			continue
+		}
+		if size+Length(closeSpans(open, mode)) <= limit {
+			safe, safeOpen = cut, slices.Clone(open)
+		}
+	}
+	if safe == 0 {
+		return line[:cut], line[cut:]
+	}
+	return line[:safe] + closeSpans(safeOpen, mode), openSpans(safeOpen) + line[safe:]
+}
+
+// htmlSpan updates the open tags with a complete tag.
+func htmlSpan(open []string, tag string) []string {
+	switch {
+	case strings.HasPrefix(tag, "</"):
+		if len(open) > 0 {
+			return open[:len(open)-1]
+		}
+		return open
+	case strings.HasSuffix(tag, "/>"):
+		return open
+	}
+	return append(open, tag)
+}
+
+// markdownSpan toggles a MarkdownV2 marker: it closes the innermost span if
+// that is the marker's, and opens a new one otherwise.
+func markdownSpan(open []string, marker string) []string {
+	if len(open) > 0 && open[len(open)-1] == marker {
+		return open[:len(open)-1]
+	}
+	return append(open, marker)
+}
+
+// closeSpans returns the markup closing the open spans, innermost first.
+func closeSpans(open []string, mode Mode) string {
+	var b strings.Builder
+	for _, span := range slices.Backward(open) {
+		if mode == HTML {
+			name, _, _ := strings.Cut(strings.Trim(span, "<>"), " ")
+			b.WriteString("</" + name + ">")
+		} else {
+			b.WriteString(span)
+		}
+	}
+	return b.String()
+}
+
+// openSpans returns the markup reopening the open spans.
+func openSpans(open []string) string {
+	return strings.Join(open, "")
+}
+
+// Length measures text the way Telegram's limits do, in UTF-16 code units.
+func Length(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,73 @@
+package markup
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCutLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       Mode
+		limit      int
+		line       string
+		head, tail string
+	}{
+		{"markdown bold", MarkdownV2, 12, "*bold words here* and more", "*bold words*", "* here* and more"},
+		{"markdown escaped markers", MarkdownV2, 12, `plain \*not bold\* text here`, `plain \*not `, `bold\* text here`},
+		{"markdown code keeps markers literal", MarkdownV2, 12, "`code a*b c d e f` end", "`code a*b c`", "` d e f` end"},
+		{"markdown underline", MarkdownV2, 12, "__under line words__ x", "__under li__", "__ne words__ x"},
+		{"markdown spoiler", MarkdownV2, 12, "||spoiler text here|| end", "||spoiler ||", "||text here|| end"},
+		{"markdown nested spans", MarkdownV2, 10, "_it *bold it* more_", "_it *bol*_", "_*d it* more_"},
+		{"markdown no empty span", MarkdownV2, 6, `1\. *bold* done`, `1\. `, "*bold* done"},
+		{"markdown cut after a link", MarkdownV2, 30, "see [a link](https://x.org) and more words", "see [a link](https://x.org) an", "d more words"},
+		{"markdown cut before a link", MarkdownV2, 24, "see [a link](https://x.org) and more", "see ", "[a link](https://x.org) and more"},
+		{"html bold", HTML, 16, "<b>bold words here</b> and more", "<b>bold word</b>", "<b>s here</b> and more"},
+		{"html nested tags", HTML, 18, "<b><i>nested words</i></b>", "<b><i>nest</i></b>", "<b><i>ed words</i></b>"},
+		{"html no empty tag", HTML, 8, "x <b>bold</b>", "x ", "<b>bold</b>"},
+		{"html entities", HTML, 9, "a &amp; b &amp; c", "a &amp; b", " &amp; c"},
+		{"html cut before an entity", HTML, 6, "tom &amp; jerry", "tom ", "&amp; jerry"},
+		{"html link reopened with its href", HTML, 32, `<a href="https://x.org">link words here</a>`, `<a href="https://x.org">link</a>`, `<a href="https://x.org"> words here</a>`},
+		{"html cut after a link", HTML, 40, `go <a href="https://x.org">a link</a> and more`, `go <a href="https://x.org">a link</a> an`, "d more"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, tail := cutLine(tt.line, tt.mode, tt.limit)
+			if head != tt.head || tail != tt.tail {
+				t.Errorf("cutLine(%q, %d) = %q, %q, want %q, %q", tt.line, tt.limit, head, tail, tt.head, tt.tail)
+			}
+			if Length(head) > tt.limit {
+				t.Errorf("head %q is longer than %d", head, tt.limit)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  Mode
+		limit int
+		text  string
+		want  []string
+	}{
+		{"short text is one part", MarkdownV2, 20, "*short*", []string{"*short*"}},
+		{"breaks at lines first", MarkdownV2, 12, "first line\n*second*", []string{"first line", "*second*"}},
+		{"long line spans reopened", MarkdownV2, 12, "line one\n*bold words here* and more", []string{"line one", "*bold words*", "* here* and ", "more"}},
+		{"html long line", HTML, 16, "<b>bold words here</b> and more", []string{"<b>bold word</b>", "<b>s here</b> an", "d more"}},
+		{"blank text", HTML, 16, "  \n ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := split(tt.text, tt.mode, tt.limit)
+			if !slices.Equal(parts, tt.want) {
+				t.Errorf("split(%q, %d) = %q, want %q", tt.text, tt.limit, parts, tt.want)
+			}
+			for _, part := range parts {
+				if Length(part) > tt.limit {
+					t.Errorf("part %q is longer than %d", part, tt.limit)
+				}
+			}
+		})
+	}
+}
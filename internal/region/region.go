@@ -0,0 +1,112 @@
+package region
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// customPrefix marks a user defined bounding box stored as
+// "box:minLat,minLon,maxLat,maxLon".
+const customPrefix = "box:"
+
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Box is a latitude/longitude rectangle. A box with MinLongitude greater than
+// MaxLongitude crosses the antimeridian.
+type Box struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+func (b Box) Contains(latitude, longitude float64) bool {
+	if latitude < b.MinLatitude || latitude > b.MaxLatitude {
+		return false
+	}
+	if b.MinLongitude <= b.MaxLongitude {
+		return longitude >= b.MinLongitude && longitude <= b.MaxLongitude
+	}
+	return longitude >= b.MinLongitude || longitude <= b.MaxLongitude
+}
+
+// Region is an area made of bounding boxes and/or polygons; a point inside any
+// of them is inside the region.
+type Region struct {
+	Id       string
+	Name     string
+	Boxes    []Box
+	Polygons [][]Point
+}
+
+func (r *Region) Contains(latitude, longitude float64) bool {
+	for _, box := range r.Boxes {
+		if box.Contains(latitude, longitude) {
+			return true
+		}
+	}
+	for _, polygon := range r.Polygons {
+		if polygonContains(polygon, latitude, longitude) {
+			return true
+		}
+	}
+	return false
+}
+
+// polygonContains is a ray casting test treating longitude/latitude as planar
+// coordinates, which is fine for the regional polygons used here.
+func polygonContains(polygon []Point, latitude, longitude float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Latitude > latitude) != (b.Latitude > latitude) &&
+			longitude < (b.Longitude-a.Longitude)*(latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Custom builds the stored reference for a user defined box.
+func Custom(box Box) (string, error) {
+	if box.MinLatitude < -90 || box.MaxLatitude > 90 || box.MinLatitude >= box.MaxLatitude {
+		return "", fmt.Errorf("latitudes must satisfy -90 <= min < max <= 90")
+	}
+	if box.MinLongitude < -180 || box.MinLongitude > 180 || box.MaxLongitude < -180 || box.MaxLongitude > 180 {
+		return "", fmt.Errorf("longitudes must be between -180 and 180")
+	}
+	return fmt.Sprintf("%s%g,%g,%g,%g", customPrefix, box.MinLatitude, box.MinLongitude, box.MaxLatitude, box.MaxLongitude), nil
+}
+
+// Parse resolves a stored reference, either a predefined region id or a
+// custom box, into a Region.
+func Parse(ref string) (*Region, error) {
+	if r, ok := Lookup(ref); ok {
+		return r, nil
+	}
+	if !strings.HasPrefix(ref, customPrefix) {
+		return nil, fmt.Errorf("unknown region %q", ref)
+	}
+	parts := strings.Split(strings.TrimPrefix(ref, customPrefix), ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("malformed custom region %q", ref)
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed custom region %q", ref)
+		}
+		values[i] = value
+	}
+	box := Box{MinLatitude: values[0], MinLongitude: values[1], MaxLatitude: values[2], MaxLongitude: values[3]}
+	return &Region{
+		Id:    ref,
+		Name:  fmt.Sprintf("Custom area %g,%g to %g,%g", box.MinLatitude, box.MinLongitude, box.MaxLatitude, box.MaxLongitude),
+		Boxes: []Box{box},
+	}, nil
+}
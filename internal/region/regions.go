@@ -0,0 +1,83 @@
+package region
+
+// predefined regions are deliberately coarse: they are meant to catch the
+// seismically active belt, not to follow coastlines.
+var predefined = []*Region{
+	{
+		Id:   "ring_of_fire",
+		Name: "🔥 Pacific Ring of Fire",
+		Boxes: []Box{
+			{MinLatitude: -56, MinLongitude: -82, MaxLatitude: 12, MaxLongitude: -66},   // Andes
+			{MinLatitude: 7, MinLongitude: -118, MaxLatitude: 33, MaxLongitude: -82},    // Central America and Mexico
+			{MinLatitude: 32, MinLongitude: -135, MaxLatitude: 60, MaxLongitude: -114},  // Cascadia and California
+			{MinLatitude: 50, MinLongitude: 160, MaxLatitude: 66, MaxLongitude: -140},   // Alaska and the Aleutians
+			{MinLatitude: 30, MinLongitude: 128, MaxLatitude: 62, MaxLongitude: 165},    // Japan, Kurils, Kamchatka
+			{MinLatitude: 10, MinLongitude: 140, MaxLatitude: 27, MaxLongitude: 148},    // Marianas
+			{MinLatitude: 5, MinLongitude: 118, MaxLatitude: 30, MaxLongitude: 132},     // Philippines, Taiwan, Ryukyu
+			{MinLatitude: -12, MinLongitude: 94, MaxLatitude: 6, MaxLongitude: 162},     // Indonesia, PNG, Solomons
+			{MinLatitude: -48, MinLongitude: 160, MaxLatitude: -10, MaxLongitude: -170}, // Vanuatu to New Zealand
+		},
+	},
+	{
+		Id:   "mediterranean",
+		Name: "🌊 Mediterranean",
+		Boxes: []Box{
+			{MinLatitude: 30, MinLongitude: -6, MaxLatitude: 46, MaxLongitude: 36},
+		},
+	},
+	{
+		Id:   "himalaya",
+		Name: "🏔️ Himalaya",
+		Polygons: [][]Point{{
+			{Latitude: 37, Longitude: 69},
+			{Latitude: 37, Longitude: 76},
+			{Latitude: 33, Longitude: 82},
+			{Latitude: 29.5, Longitude: 88},
+			{Latitude: 29.5, Longitude: 97.5},
+			{Latitude: 26, Longitude: 97.5},
+			{Latitude: 26, Longitude: 88},
+			{Latitude: 27, Longitude: 82},
+			{Latitude: 31, Longitude: 75},
+			{Latitude: 33, Longitude: 69},
+		}},
+	},
+	{
+		Id:   "caribbean",
+		Name: "🏝️ Caribbean",
+		Boxes: []Box{
+			{MinLatitude: 9, MinLongitude: -88, MaxLatitude: 24, MaxLongitude: -59},
+		},
+	},
+	{
+		Id:   "east_african_rift",
+		Name: "🌋 East African Rift",
+		Polygons: [][]Point{{
+			{Latitude: 16, Longitude: 38},
+			{Latitude: 12, Longitude: 44},
+			{Latitude: 3, Longitude: 40},
+			{Latitude: -16, Longitude: 37},
+			{Latitude: -16, Longitude: 33},
+			{Latitude: -4, Longitude: 28},
+			{Latitude: 3, Longitude: 29},
+			{Latitude: 9, Longitude: 35},
+		}},
+	},
+}
+
+var byId = map[string]*Region{}
+
+func init() {
+	for _, r := range predefined {
+		byId[r.Id] = r
+	}
+}
+
+// All returns the predefined regions in display order.
+func All() []*Region {
+	return predefined
+}
+
+func Lookup(id string) (*Region, bool) {
+	r, ok := byId[id]
+	return r, ok
+}
@@ -0,0 +1,20 @@
+package scheduler
+
+import (
+	"alerts/internal/geo"
+	"alerts/internal/seismic"
+	"alerts/internal/templates"
+	"alerts/model"
+)
+
+// alertData fills in the alert template, estimating how noticeable the quake
+// is at the user's watch location, or at the epicenter without one.
+func alertData(event *model.Event, address *model.Address, watch *model.Location) *templates.AlertData {
+	data := &templates.AlertData{Event: event, Address: address, MapURL: mapURL(event)}
+	data.Felt = seismic.FeltLikelihood(event.Magnitude, event.Depth, 0)
+	if watch != nil {
+		data.Distance = geo.Distance(watch.Latitude, watch.Longitude, event.Latitude, event.Longitude)
+		data.Intensity = seismic.EstimateMMI(event.Magnitude, event.Depth, data.Distance)
+	}
+	return data
+}
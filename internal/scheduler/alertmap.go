@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"alerts/internal/markup"
+	"alerts/internal/staticmap"
+	"alerts/internal/telegram"
+	"alerts/internal/templates"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"log"
+	"math"
+	"time"
+)
+
+// recentWindow is how far back the earlier quakes drawn around an epicenter go.
+const recentWindow = 7 * 24 * time.Hour
+
+// alertMaps renders and uploads the alert map images for one poll. A map
+// without a watch location is the same for every user, so after its first
+// upload the Telegram file id is sent instead of the image.
+type alertMaps struct {
+	recent  []*model.Event
+	fileIds map[string]string
+}
+
+func newAlertMaps() *alertMaps {
+	now := time.Now()
+	recent, err := repository.GetEventsBetween(now.Add(-recentWindow), now)
+	if err != nil {
+		log.Println("Error fetching recent events for the alert map", err.Error())
+	}
+	return &alertMaps{recent: recent, fileIds: map[string]string{}}
+}
+
+// feltRadiusKm is a rough radius of noticeable shaking, only used to size
+// the circle drawn around the epicenter.
+func feltRadiusKm(magnitude float64) float64 {
+	return math.Pow(10, 0.5*magnitude-0.8)
+}
+
+func (a *alertMaps) render(event *model.Event, canonicalId string, watch *model.Location) ([]byte, error) {
+	radius := feltRadiusKm(event.Magnitude)
+	m := staticmap.New(event.Latitude, event.Longitude, max(radius*3/111, 4))
+	if watch != nil && !m.Include(watch.Latitude, watch.Longitude) {
+		watch = nil
+	}
+	m.Circle(event.Latitude, event.Longitude, radius, staticmap.Shaking)
+	for _, earlier := range a.recent {
+		if earlier.Id == canonicalId || !m.Contains(earlier.Latitude, earlier.Longitude) {
+			continue
+		}
+		m.Marker(earlier.Latitude, earlier.Longitude, 2+max(earlier.Magnitude-3, 0), staticmap.Recent)
+	}
+	if watch != nil {
+		m.Marker(watch.Latitude, watch.Longitude, 5, staticmap.Watch)
+	}
+	m.Marker(event.Latitude, event.Longitude, 6, staticmap.Epicenter)
+	return m.PNG()
+}
+
+// send sends the alert as a photo of the map with the text as its caption.
+// Text too long for a caption follows the photo as its own message.
+func (a *alertMaps) send(ctx context.Context, chatId int64, text string, event *model.Event, canonicalId string, watch *model.Location, replyTo int64) (*model.Message, error) {
+	params := &telegram.SendPhotoParams{ChatID: chatId, FileName: "epicenter.png", ReplyToMessageID: replyTo, AllowSendingWithoutReply: replyTo != 0}
+	captioned := markup.Length(text) <= markup.MaxCaptionLength
+	if captioned {
+		params.Caption = text
+		params.ParseMode = string(templates.Mode())
+	}
+	shared := watch == nil
+	if fileId, ok := a.fileIds[canonicalId]; ok && shared {
+		params.Photo = fileId
+	} else {
+		image, err := a.render(event, canonicalId, watch)
+		if err != nil {
+			return nil, err
+		}
+		params.PhotoData = image
+	}
+	sent, err := telegram.Bot.SendPhoto(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if shared && params.PhotoData != nil && len(sent.Photo) > 0 {
+		a.fileIds[canonicalId] = sent.Photo[len(sent.Photo)-1].FileId
+	}
+	if !captioned {
+		return sendFormatted(ctx, chatId, text, 0)
+	}
+	return sent, nil
+}
@@ -0,0 +1,29 @@
+package scheduler
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff computes retry delays that double on every consecutive failure up
+// to max, with jitter so replicas and loops don't retry in lockstep.
+type backoff struct {
+	base     time.Duration
+	max      time.Duration
+	failures int
+}
+
+func (b *backoff) next() time.Duration {
+	b.failures++
+	delay := b.max
+	if shift := b.failures - 1; shift < 16 {
+		if d := b.base << shift; d < b.max {
+			delay = d
+		}
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func (b *backoff) reset() {
+	b.failures = 0
+}
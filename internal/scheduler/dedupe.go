@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"alerts/config"
+	"alerts/internal/geo"
+	"alerts/model"
+	"alerts/repository"
+	"math"
+	"time"
+)
+
+// canonicalEventId maps an event to the id it was first alerted under. Events
+// are matched on any shared id first, and when the id lists don't overlap (a
+// different network, or a different source altogether) on origin time and
+// epicentre distance. The result is persisted so later polls match directly.
+func canonicalEventId(event *model.Event) (string, error) {
+	canonicalId, err := repository.GetCanonicalEventId(event.Ids)
+	if err != nil {
+		return "", err
+	}
+	if canonicalId == "" {
+		canonicalId, err = associateEvent(event)
+		if err != nil {
+			return "", err
+		}
+	}
+	if canonicalId == "" {
+		canonicalId = event.Id
+	}
+	if err = repository.SaveEvent(canonicalId, event); err != nil {
+		return "", err
+	}
+	return canonicalId, nil
+}
+
+// associateEvent returns the closest stored event within the configured time
+// window and radius, or an empty string if there is none.
+func associateEvent(event *model.Event) (string, error) {
+	window := time.Duration(config.BotConf.AssociationWindow) * time.Second
+	occurred := time.UnixMilli(event.Time)
+	candidates, err := repository.GetEventsBetween(occurred.Add(-window), occurred.Add(window))
+	if err != nil {
+		return "", err
+	}
+	closestId := ""
+	closest := math.MaxFloat64
+	for _, candidate := range candidates {
+		distance := geo.Distance(event.Latitude, event.Longitude, candidate.Latitude, candidate.Longitude)
+		if distance <= config.BotConf.AssociationRadius && distance < closest {
+			closest = distance
+			closestId = candidate.Id
+		}
+	}
+	return closestId, nil
+}
@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// drainContext returns a context that outlives ctx by timeout: it is not
+// cancelled when ctx is, only timeout after that.
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	drain, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(timeout, cancel)
+	})
+	return drain, func() {
+		stop()
+		cancel()
+	}
+}
+
+// sleepContext waits for d, returning early with ctx's error if it is
+// cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
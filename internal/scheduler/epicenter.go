@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"alerts/internal/telegram"
+	"alerts/model"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// sendEpicenter follows an alert with a native map pin of the epicenter,
+// replying to the alert. Telegram requires a venue to have a title and an
+// address, so events without a place name get a plain location.
+func sendEpicenter(ctx context.Context, chatId int64, event *model.Event, address *model.Address, replyTo *model.Message) error {
+	var replyToId int64
+	if replyTo != nil {
+		replyToId = replyTo.MessageID
+	}
+	if event.Place == "" {
+		_, err := telegram.Bot.SendLocation(ctx, &telegram.SendLocationParams{
+			ChatID:           chatId,
+			Latitude:         event.Latitude,
+			Longitude:        event.Longitude,
+			ReplyToMessageID: replyToId,
+		})
+		return err
+	}
+	_, err := telegram.Bot.SendVenue(ctx, &telegram.SendVenueParams{
+		ChatID:           chatId,
+		Latitude:         event.Latitude,
+		Longitude:        event.Longitude,
+		Title:            fmt.Sprintf("M%.1f %s", event.Magnitude, event.Place),
+		Address:          venueAddress(event, address),
+		ReplyToMessageID: replyToId,
+	})
+	return err
+}
+
+// venueAddress is the reverse geocoded address, or the coordinates when the
+// epicenter is offshore and Nominatim has nothing.
+func venueAddress(event *model.Event, address *model.Address) string {
+	parts := []string{}
+	if address != nil {
+		for _, part := range []string{address.State, address.County, address.Country} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%.3f, %.3f", event.Latitude, event.Longitude)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"alerts/config"
+	"alerts/internal/templates"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"fmt"
+	"math"
+)
+
+func mapURL(event *model.Event) string {
+	return fmt.Sprintf(config.BotConf.MapURL, event.Longitude, event.Latitude, event.Longitude, event.Latitude)
+}
+
+// magnitudeRevision compares the event with the stored canonical event and,
+// when the magnitude moved by at least RevisionThreshold, records the new
+// magnitude and returns the old one.
+func magnitudeRevision(canonicalId string, event *model.Event) (float64, bool, error) {
+	stored, err := repository.GetEvent(canonicalId)
+	if err != nil || stored == nil {
+		return 0, false, err
+	}
+	if math.Abs(stored.Magnitude-event.Magnitude) < config.BotConf.RevisionThreshold {
+		return 0, false, nil
+	}
+	if err = repository.UpdateEventMagnitude(canonicalId, event.Magnitude); err != nil {
+		return 0, false, err
+	}
+	return stored.Magnitude, true, nil
+}
+
+func sendRevision(ctx context.Context, chatId int64, lang string, event *model.Event, previousMagnitude float64) error {
+	message, err := templates.Render(templates.Revision, lang, &templates.RevisionData{
+		Event:             event,
+		PreviousMagnitude: previousMagnitude,
+		MapURL:            mapURL(event),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = sendFormatted(ctx, chatId, message, 0); err != nil {
+		return err
+	}
+	recordLastAlert(chatId, event.Title)
+	return nil
+}
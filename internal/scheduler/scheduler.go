@@ -2,212 +2,390 @@ package scheduler
 
 import (
 	"alerts/config"
+	"alerts/internal/bot"
 	"alerts/internal/fetcher"
+	"alerts/internal/filter"
+	"alerts/internal/health"
+	"alerts/internal/leader"
+	"alerts/internal/telegram"
+	"alerts/internal/templates"
 	"alerts/model"
 	"alerts/repository"
-	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"strings"
+	"slices"
 	"sync"
 	"time"
 )
 
-var ticker = time.NewTicker(time.Second * 15)
-var addUser = time.NewTicker(time.Second * 14)
 var client http.Client
 var addressClient http.Client
 
-func PollAddUser(wg *sync.WaitGroup) {
-	defer addUser.Stop()
+var alertCheck = health.Register("earthquake_poll")
+var userCheck = health.Register("telegram_updates")
+
+const maxRetryDelay = 5 * time.Minute
+
+// PollAddUser fetches Telegram updates every UserTicker seconds, backing off
+// exponentially while the fetch keeps failing.
+func PollAddUser(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
+	interval := time.Duration(config.BotConf.UserTicker) * time.Second
+	retry := &backoff{base: interval, max: maxRetryDelay}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 	for {
 		select {
-		case <-addUser.C:
+		case <-ctx.Done():
+			log.Println("Stopped fetching Telegram updates")
+			return
+		case <-timer.C:
+			if !leader.IsLeader() {
+				timer.Reset(interval)
+				continue
+			}
 			log.Println("Fetching chatId from Telegram")
-			fetcher.FetchChatId()
+			if _, err := fetcher.FetchChatId(ctx); err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				failures := userCheck.Failure(err)
+				delay := retry.next()
+				log.Printf("Fetching Telegram updates failed %d times in a row, retrying in %s: %s", failures, delay, err)
+				timer.Reset(delay)
+				continue
+			}
+			userCheck.Success()
+			retry.reset()
+			timer.Reset(interval)
 		}
 	}
 }
 
-func PollingAlerts(wg *sync.WaitGroup) {
-	defer ticker.Stop()
+// PollingAlerts fetches events every AlertTicker seconds and alerts the
+// subscribers, backing off exponentially while polling keeps failing.
+func PollingAlerts(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
+	sources := fetcher.NewSources(config.BotConf.Sources)
+	interval := time.Duration(config.BotConf.AlertTicker) * time.Second
+	retry := &backoff{base: interval, max: maxRetryDelay}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 	for {
 		select {
-		case <-ticker.C:
-			log.Println("Polling FetchEarthQuake function")
-			data := fetcher.FetchEarthQuake()
-			user := repository.GetFromTelegramBot()
-			pollingAlertUtil(user, data)
+		case <-ctx.Done():
+			log.Println("Stopped polling earthquake sources")
+			return
+		case <-timer.C:
+			if !leader.IsLeader() {
+				timer.Reset(interval)
+				continue
+			}
+			log.Println("Polling earthquake sources")
+			if err := pollAlerts(ctx, sources); err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				failures := alertCheck.Failure(err)
+				delay := retry.next()
+				log.Printf("Polling alerts failed %d times in a row, retrying in %s: %s", failures, delay, err)
+				timer.Reset(delay)
+				continue
+			}
+			alertCheck.Success()
+			retry.reset()
+			timer.Reset(interval)
 		}
 	}
 }
 
-func pollingAlertUtil(user []*model.InsertBotUser, data *model.Data) {
+func pollAlerts(ctx context.Context, sources []fetcher.Source) error {
+	events, err := fetcher.FetchEvents(ctx, sources)
+	if err != nil {
+		return err
+	}
+	user := repository.GetFromTelegramBot()
+	undelivered, err := pollingAlertUtil(ctx, user, events)
+	if err != nil || undelivered > 0 {
+		// The events were consumed by the incremental fetch, so start from the
+		// full feed again. Only alerts that went out are in sent_alerts, so the
+		// next poll retries the rest without repeating the others.
+		fetcher.ResetFeedState()
+	}
+	if undelivered > 0 {
+		log.Printf("%d deliveries failed, retrying them on the next poll", undelivered)
+	}
+	return err
+}
+
+// pollingAlertUtil stops picking up new users once ctx is cancelled, but a
+// send that has already started is given ShutdownTimeout to complete so a
+// shutdown doesn't cut an alert off halfway. It returns how many chats could
+// not be delivered to.
+func pollingAlertUtil(ctx context.Context, user []*model.InsertBotUser, events []*model.Event) (int, error) {
+	sendCtx, cancel := drainContext(ctx, time.Duration(config.BotConf.ShutdownTimeout)*time.Second)
+	defer cancel()
 
 	size := len(user)
-	dataSize := len(data.Features)
+	dataSize := len(events)
 	addresses := []*model.Address{}
+	canonicalIds := []string{}
+	// revisions holds the previously alerted magnitude of events whose
+	// magnitude has since been revised, by index into events.
+	revisions := map[int]float64{}
+	// mainshocks holds the mainshock of each event that is an aftershock.
+	mainshocks := map[int]*model.Event{}
 
 	for j := range dataSize {
-		address, err := fetchLocation(data.Features[j].Geo)
+		address, err := fetchLocation(ctx, events[j].Latitude, events[j].Longitude)
 
 		if err != nil {
 			log.Println("Error fetching the location", err.Error())
-			return
+			return 0, err
 		}
 		addresses = append(addresses, address)
 		log.Println("Country code is ", addresses[j].CountryCode)
-	}
 
-	for i := range size {
-		keyBoardSent, err := repository.GetKeyBoardSent(user[i].ChatId)
+		canonicalId, err := canonicalEventId(events[j])
 		if err != nil {
-			log.Println("Error getting keyboard sent flag:", err)
-			panic("can't get the flag value for keyboard sent")
+			log.Println("Error resolving the canonical event id", err.Error())
+			return 0, err
+		}
+		canonicalIds = append(canonicalIds, canonicalId)
+		if err = repository.SetEventCountry(canonicalId, address.CountryCode); err != nil {
+			return 0, err
 		}
-		country, err := repository.GetCountry(user[i].ChatId)
-
-		if err == nil && country == "" {
-			if !keyBoardSent {
-				if err = SendKeyBoard(user[i].ChatId); err != nil {
-					log.Println("ERROR SENDING KEYBOARD TO TELEGRAM", err.Error())
-					return
-				} else {
-					repository.SetKeyBoardSent(user[i].ChatId)
-				}
-			}
-
-		} else if err != nil {
-			log.Println("ERROR FETCHING THE PREFERRED COUNTRY", err.Error())
-			return
-		} else {
-			for j := range dataSize {
-				if country == addresses[j].CountryCode || country == "all" {
-
-					count, err := repository.GetAlertCount(data.Features[j].Id, user[i].ChatId)
-
-					if err != nil {
-						log.Println("Not able to fetch count", err.Error())
-						return
-					} else if count == 0 {
-						req := new(model.InsertAlertRequest)
-						req.ChatId = user[i].ChatId
-						req.EarthQuakeId = data.Features[j].Id
-						err = repository.InsertIntoSentAlert(req)
-						if err != nil {
-							log.Println("Failed to insert alert data into database", err.Error())
-							return
-						}
-
-						timeOccured := time.UnixMilli(data.Features[j].Properties.Time).UTC()
-						formatted := fmt.Sprintf("%s", timeOccured.Format("January 2, 2006 at 3:04 PM MST"))
-						var tsunamiAlert string
-						if data.Features[j].Properties.Tsunami == 0 {
-							tsunamiAlert = "No"
-						} else {
-							tsunamiAlert = "Yes"
-						}
-						mapURL := fmt.Sprintf(config.BotConf.MapURL, data.Features[j].Geo.Coordinates[0], data.Features[j].Geo.Coordinates[1], data.Features[j].Geo.Coordinates[0], data.Features[j].Geo.Coordinates[1])
-
-						message := fmt.Sprintf(
-							`🌍 *Earthquake Alert\!* 🌍
-
-*%s*
 
-📍 *Location:* %s, %s, %s
-📏 *Magnitude:* %s
-🕒 *Alert Time:* %s
-📡 *Depth:* %s km
-🌊🚨 *Tsunami Alert:* %s
-🗺️ [Click here to view location](%s)
+		previous, revised, err := magnitudeRevision(canonicalId, events[j])
+		if err != nil {
+			log.Println("Error checking for a magnitude revision", err.Error())
+			return 0, err
+		}
+		if revised {
+			revisions[j] = previous
+		}
+	}
 
-⚠️ *Stay Safe:*
-\- Move to an open area away from buildings
-\- Avoid elevators
-\- Drop, Cover, and Hold On\!`,
-							escapeMdV2(data.Features[j].Properties.Title),
-							escapeMdV2(addresses[j].State),
-							escapeMdV2(addresses[j].County),
-							escapeMdV2(addresses[j].Country),
-							escapeMdV2(fmt.Sprintf("%.2f", data.Features[j].Properties.Magnitude)),
-							escapeMdV2(formatted),
-							escapeMdV2(fmt.Sprintf("%.2f", data.Features[j].Geo.Coordinates[2])),
-							escapeMdV2(tsunamiAlert),
-							mapURL,
-						)
+	// Mainshocks are resolved once the whole poll is stored, oldest first:
+	// the feed lists the newest quake first, so an aftershock would otherwise
+	// be checked before its mainshock from the same poll exists.
+	order := make([]int, dataSize)
+	for j := range order {
+		order[j] = j
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(events[a].Time, events[b].Time) })
+	for _, j := range order {
+		mainshock, err := mainshockOf(canonicalIds[j], events[j])
+		if err != nil {
+			log.Println("Error looking up the mainshock", err.Error())
+			return 0, err
+		}
+		if mainshock != nil {
+			mainshocks[j] = mainshock
+		}
+	}
 
-						if err = SendAlertToTelegram(user[i].ChatId, message); err != nil {
-							log.Println("ERROR SENDING MESSAGE TO TELEGRAM", err.Error())
-							return
-						}
-					}
+	// Swarms are looked for once every event of the poll is stored, so the
+	// order of the feed doesn't matter.
+	swarms := map[int]*model.Swarm{}
+	for j := range dataSize {
+		swarm, err := detectSwarm(canonicalIds[j], events[j], mainshocks[j])
+		if err != nil {
+			log.Println("Error looking for a swarm", err.Error())
+			return 0, err
+		}
+		if swarm != nil {
+			swarms[j] = swarm
+		}
+	}
 
-				}
+	var maps *alertMaps
+	if config.BotConf.AlertMap && dataSize > 0 {
+		maps = newAlertMaps()
+	}
+	b := &batch{
+		events:       events,
+		addresses:    addresses,
+		canonicalIds: canonicalIds,
+		revisions:    revisions,
+		mainshocks:   mainshocks,
+		swarms:       swarms,
+		maps:         maps,
+	}
 
+	// A chat that can't be reached is logged and skipped so the others still
+	// get their alerts. Nothing is recorded for an alert that didn't go out,
+	// so counting the failures lets the caller retry them on the next poll.
+	undelivered := 0
+	for i := range size {
+		if err := ctx.Err(); err != nil {
+			return undelivered, err
+		}
+		err := deliver(sendCtx, user[i].ChatId, b)
+		if wait := telegram.RetryAfter(err); wait > 0 {
+			log.Printf("Rate limited delivering to chat %d, retrying in %s", user[i].ChatId, wait)
+			if err = sleepContext(sendCtx, wait); err == nil {
+				err = deliver(sendCtx, user[i].ChatId, b)
 			}
 		}
-
+		switch {
+		case err == nil:
+		case telegram.IsForbidden(err):
+			log.Printf("Chat %d blocked the bot or was removed, skipping it", user[i].ChatId)
+		default:
+			log.Printf("Delivering alerts to chat %d failed: %s", user[i].ChatId, err)
+			undelivered++
+		}
 	}
+	if err := sendSequenceSummaries(sendCtx); err != nil {
+		log.Println("Error sending the sequence summaries", err.Error())
+	}
+	return undelivered, nil
 }
 
-func SendAlertToTelegram(chatId int64, message string) error {
-	botToken := config.BotConf.BotToken
-	telegramAPI := fmt.Sprintf("%s%s/sendMessage", config.BotConf.TelegramDomain, botToken)
-
-	telegramMessage := &model.TelegramMessage{
-		ChatID:    chatId,
-		Text:      message,
-		ParseMode: "MarkdownV2",
-	}
+// batch is what one poll found, shared by every chat it is delivered to. The
+// maps are keyed by index into events.
+type batch struct {
+	events       []*model.Event
+	addresses    []*model.Address
+	canonicalIds []string
+	revisions    map[int]float64
+	mainshocks   map[int]*model.Event
+	swarms       map[int]*model.Swarm
+	maps         *alertMaps
+}
 
-	body, err := json.Marshal(telegramMessage)
+// deliver sends one chat everything in the batch it subscribed to, stopping
+// at the first error. An alert is recorded in sent_alerts only once it went
+// out, so calling deliver again resumes where it stopped.
+func deliver(ctx context.Context, chatId int64, b *batch) error {
+	keyBoardSent, err := repository.GetKeyBoardSent(chatId)
 	if err != nil {
-		log.Println("failed to marshal message:", err)
+		log.Println("Error getting keyboard sent flag:", err)
 		return err
 	}
-
-	req, err := http.NewRequest(http.MethodPost, telegramAPI, bytes.NewBuffer(body))
+	country, err := repository.GetCountry(chatId)
 	if err != nil {
-		log.Println("error creating Telegram request:", err)
+		log.Println("ERROR FETCHING THE PREFERRED COUNTRY", err.Error())
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "earthquake-alert-bot/1.0")
-
-	client := http.Client{}
-	resp, err := client.Do(req)
+	regions, err := filter.UserRegions(chatId)
 	if err != nil {
-		log.Println("error sending request to Telegram:", err)
+		log.Println("ERROR FETCHING THE USER REGIONS", err.Error())
 		return err
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	if country == "" && len(regions) == 0 {
+		if !keyBoardSent {
+			if err = bot.SendKeyBoard(ctx, chatId); err != nil {
+				log.Println("ERROR SENDING KEYBOARD TO TELEGRAM", err.Error())
+				return err
+			}
+			repository.SetKeyBoardSent(chatId)
+		}
+		return nil
+	}
+
+	preferences, err := repository.GetUserPreferences(chatId)
 	if err != nil {
-		log.Println("error reading response from Telegram:", err)
+		log.Println("ERROR FETCHING THE USER PREFERENCES", err.Error())
 		return err
 	}
+	for j, event := range b.events {
+		address, canonicalId, mainshock := b.addresses[j], b.canonicalIds[j], b.mainshocks[j]
+		if swarm := b.swarms[j]; swarm != nil && swarm.MaxMagnitude >= preferences.MinMagnitude && filter.Covers(country, regions, event, address.CountryCode) {
+			if err = sendSwarmNotice(ctx, chatId, preferences.Language, swarm, event.Place); err != nil {
+				log.Println("ERROR SENDING SWARM NOTICE TO TELEGRAM", err.Error())
+				return err
+			}
+		}
+		if event.Tsunami != 0 && tsunamiRecipient(country, regions, preferences.Watch, event, address) {
+			sent, err := sendTsunamiWarning(ctx, chatId, preferences, event, address, canonicalId, b.maps)
+			if err != nil {
+				log.Println("ERROR SENDING TSUNAMI WARNING TO TELEGRAM", err.Error())
+				return err
+			}
+			if sent {
+				continue
+			}
+		}
+		if event.Magnitude < preferences.MinMagnitude || !filter.Passes(preferences, event) {
+			continue
+		}
+		if !filter.Covers(country, regions, event, address.CountryCode) {
+			continue
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Telegram API returned status %d: %s", resp.StatusCode, string(respBody))
-		return fmt.Errorf("telegram API error: %s", respBody)
-	}
+		count, err := repository.GetAlertCount(canonicalId, chatId)
+		if err != nil {
+			log.Println("Not able to fetch count", err.Error())
+			return err
+		}
+		if count > 0 {
+			if previous, ok := b.revisions[j]; ok {
+				if err = sendRevision(ctx, chatId, preferences.Language, event, previous); err != nil {
+					log.Println("ERROR SENDING REVISION TO TELEGRAM", err.Error())
+					return err
+				}
+			}
+			continue
+		}
+
+		req := &model.InsertAlertRequest{ChatId: chatId, EarthQuakeId: canonicalId}
+		if summarised(mainshock, event) {
+			if err = repository.InsertIntoSentAlert(req); err != nil {
+				log.Println("Failed to insert alert data into database", err.Error())
+				return err
+			}
+			if err = repository.QueueSummaryEvent(chatId, mainshock.Id, canonicalId); err != nil {
+				log.Println("Failed to queue the aftershock", err.Error())
+				return err
+			}
+			continue
+		}
 
-	log.Println("Telegram message sent successfully:", string(respBody))
+		data := alertData(event, address, preferences.Watch)
+		data.Mainshock = mainshock
+		message, err := templates.Render(templates.Alert, preferences.Language, data)
+		if err != nil {
+			log.Println("Failed to render the alert", err.Error())
+			return err
+		}
+		replyTo, err := sequenceReply(chatId, mainshock)
+		if err != nil {
+			log.Println("Failed to look up the mainshock alert", err.Error())
+			return err
+		}
+		sent, err := sendAlert(ctx, chatId, message, event, canonicalId, preferences.Watch, b.maps, replyTo)
+		if err != nil {
+			log.Println("ERROR SENDING MESSAGE TO TELEGRAM", err.Error())
+			return err
+		}
+		if err = repository.InsertIntoSentAlert(req); err != nil {
+			log.Println("Failed to insert alert data into database", err.Error())
+			return err
+		}
+		recordAlertMessage(canonicalId, chatId, event, sent)
+		if preferences.SendLocation {
+			if err = sendEpicenter(ctx, chatId, event, address, sent); err != nil {
+				log.Println("ERROR SENDING EPICENTER TO TELEGRAM", err.Error())
+			}
+		}
+	}
 	return nil
 }
 
-func fetchLocation(m *model.Geometry) (*model.Address, error) {
+func fetchLocation(ctx context.Context, latitude, longitude float64) (*model.Address, error) {
 
 	openStreetMapDomain := config.BotConf.OpenstreetmapDomain
-	url := fmt.Sprintf("%s?format=jsonv2&lat=%f&lon=%f", openStreetMapDomain, m.Coordinates[1], m.Coordinates[0])
+	url := fmt.Sprintf("%s?format=jsonv2&lat=%f&lon=%f", openStreetMapDomain, latitude, longitude)
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		log.Println("Error creating request:", err)
 		return nil, err
@@ -237,96 +415,3 @@ func fetchLocation(m *model.Geometry) (*model.Address, error) {
 
 	return &address.Address, nil
 }
-
-func SendKeyBoard(chatId int64) error {
-	botToken := config.BotConf.BotToken
-
-	telegramAPI := fmt.Sprintf("%s%s/sendMessage", config.BotConf.TelegramDomain, botToken)
-	keyboard := model.InlineKeyBoardMarkup{
-		InlineKeyBoard: [][]model.InlineKeyBoardButton{
-			{
-				{Text: "🇬🇧 UK", CallbackData: "gb"},
-				{Text: "🇺🇸 USA", CallbackData: "us"},
-			},
-			{
-				{Text: "🇮🇳 India", CallbackData: "in"},
-				{Text: "🇮🇷 Iran", CallbackData: "ir"},
-			},
-			{
-				{Text: "🇯🇵 Japan", CallbackData: "jp"},
-				{Text: "🇮🇹 Italy", CallbackData: "it"},
-			},
-			{
-				{Text: "🇮🇩 Indonesia", CallbackData: "id"},
-				{Text: "🇷🇺 Russia", CallbackData: "ru"},
-			}, {
-				{Text: "🌍 Global", CallbackData: "all"},
-			},
-		},
-	}
-	msg := model.TelegramMessageWithKeyboard{
-		ChatID:      chatId,
-		Text:        "Please select your preferred country for earthquake alerts:",
-		ReplyMarkup: keyboard,
-	}
-
-	body, err := json.Marshal(msg)
-	if err != nil {
-		log.Println("failed to marshal message:", err)
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, telegramAPI, bytes.NewBuffer(body))
-	if err != nil {
-		log.Println("error creating Telegram request:", err)
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "earthquake-alert-bot/1.0")
-
-	client := http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Println("error sending request to Telegram:", err)
-		return err
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("error reading response from Telegram:", err)
-		return err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Telegram API returned status %d: %s", resp.StatusCode, string(respBody))
-		return fmt.Errorf("telegram API error: %s", respBody)
-	}
-
-	log.Println("Telegram message sent successfully:", string(respBody))
-	return nil
-}
-
-func escapeMdV2(text string) string {
-	replacer := strings.NewReplacer(
-		"_", "\\_",
-		"*", "\\*",
-		"[", "\\[",
-		"]", "\\]",
-		"(", "\\(",
-		")", "\\)",
-		"~", "\\~",
-		"`", "\\`",
-		">", "\\>",
-		"#", "\\#",
-		"+", "\\+",
-		"-", "\\-",
-		"=", "\\=",
-		"|", "\\|",
-		"{", "\\{",
-		"}", "\\}",
-		".", "\\.",
-		"!", "\\!",
-	)
-	return replacer.Replace(text)
-}
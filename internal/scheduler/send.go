@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"alerts/internal/markup"
+	"alerts/internal/telegram"
+	"alerts/internal/templates"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"log"
+)
+
+// sendAlert sends an alert with its map when maps is set, falling back to
+// the text alone if the map can't be rendered or sent. A non-zero replyTo
+// threads the alert under that message.
+func sendAlert(ctx context.Context, chatId int64, text string, event *model.Event, canonicalId string, watch *model.Location, maps *alertMaps, replyTo int64) (*model.Message, error) {
+	if maps == nil {
+		return sendFormatted(ctx, chatId, text, replyTo)
+	}
+	sent, err := maps.send(ctx, chatId, text, event, canonicalId, watch, replyTo)
+	if err != nil && !telegram.IsForbidden(err) {
+		log.Println("Failed to send the alert map, sending text only", err.Error())
+		return sendFormatted(ctx, chatId, text, replyTo)
+	}
+	return sent, err
+}
+
+// sendFormatted sends rendered template output in the configured parse mode,
+// split into as many messages as Telegram's length limit requires, and
+// returns the first message sent. Only the first message replies to replyTo.
+func sendFormatted(ctx context.Context, chatId int64, text string, replyTo int64) (*model.Message, error) {
+	mode := templates.Mode()
+	var first *model.Message
+	for _, part := range markup.Split(text, mode) {
+		params := &telegram.SendMessageParams{ChatID: chatId, Text: part, ParseMode: string(mode)}
+		if first == nil && replyTo != 0 {
+			params.ReplyToMessageID = replyTo
+			params.AllowSendingWithoutReply = true
+		}
+		sent, err := telegram.Bot.SendMessage(ctx, params)
+		if err != nil {
+			return first, err
+		}
+		if first == nil {
+			first = sent
+		}
+	}
+	return first, nil
+}
+
+// recordLastAlert keeps title as the chat's last alert for /status. Every
+// kind of alert counts: regular ones, tsunami warnings, revisions, swarm
+// notices and sequence summaries. Failing to is only logged.
+func recordLastAlert(chatId int64, title string) {
+	if err := repository.UpdateLastAlert(chatId, title); err != nil {
+		log.Println("Failed to record the last alert", err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"alerts/config"
+	"alerts/internal/geo"
+	"alerts/internal/i18n"
+	"alerts/internal/seismic"
+	"alerts/internal/telegram"
+	"alerts/internal/templates"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"log"
+	"time"
+)
+
+// mainshockOf returns the mainshock of the sequence event belongs to: the
+// largest stored quake of at least SequenceMagnitude whose Gardner–Knopoff
+// window contains it, or nil when it isn't an aftershock. The match is
+// persisted, so an aftershock stays in its sequence when later quakes arrive.
+func mainshockOf(canonicalId string, event *model.Event) (*model.Event, error) {
+	stored, err := repository.GetEvent(canonicalId)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil && stored.MainshockId != "" {
+		return repository.GetEvent(stored.MainshockId)
+	}
+	occurred := time.UnixMilli(event.Time)
+	from := occurred.AddDate(0, 0, -config.BotConf.EventRetentionDays)
+	candidates, err := repository.GetMainshockCandidates(max(config.BotConf.SequenceMagnitude, event.Magnitude), from, occurred)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range candidates {
+		if candidate.Id == canonicalId || candidate.Magnitude <= event.Magnitude {
+			continue
+		}
+		distance, window := seismic.AftershockWindow(candidate.Magnitude)
+		if occurred.Sub(time.UnixMilli(candidate.Time)) > window ||
+			geo.Distance(event.Latitude, event.Longitude, candidate.Latitude, candidate.Longitude) > distance {
+			continue
+		}
+		// A large aftershock has a window of its own; its aftershocks still
+		// belong to the original sequence.
+		if candidate.MainshockId != "" {
+			if candidate, err = repository.GetEvent(candidate.MainshockId); err != nil || candidate == nil {
+				return nil, err
+			}
+		}
+		return candidate, repository.SetMainshock(canonicalId, candidate.Id)
+	}
+	return nil, nil
+}
+
+// summarised reports whether an aftershock is small enough to be held back
+// for the sequence summary instead of alerted on its own.
+func summarised(mainshock, event *model.Event) bool {
+	return mainshock != nil && event.Magnitude < config.BotConf.AftershockSummary
+}
+
+// sequenceReply returns the message an alert about an aftershock of
+// mainshock replies to in chatId, or 0 to send it on its own.
+func sequenceReply(chatId int64, mainshock *model.Event) (int64, error) {
+	if mainshock == nil {
+		return 0, nil
+	}
+	return repository.GetAlertMessageId(mainshock.Id, chatId)
+}
+
+// recordAlertMessage keeps the message an alert went out as for threading,
+// and the event as the chat's last alert for /status. Failing to is only
+// logged, as it just leaves later aftershocks unthreaded.
+func recordAlertMessage(canonicalId string, chatId int64, event *model.Event, sent *model.Message) {
+	if sent == nil {
+		return
+	}
+	if err := repository.SetAlertMessageId(canonicalId, chatId, sent.MessageID); err != nil {
+		log.Println("Failed to record the alert message", err.Error())
+	}
+	recordLastAlert(chatId, event.Title)
+}
+
+// sendSequenceSummaries sends the aftershocks held back from each chat as one
+// digest per sequence, threaded under the mainshock alert, once the oldest of
+// them has waited SummaryInterval minutes. A summary that fails stays queued
+// and is retried on the next poll.
+func sendSequenceSummaries(ctx context.Context) error {
+	if config.BotConf.AftershockSummary <= 0 {
+		return nil
+	}
+	due := time.Now().Add(-time.Duration(config.BotConf.SummaryInterval) * time.Minute)
+	summaries, err := repository.GetDueSummaries(due)
+	if err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := sendSequenceSummary(ctx, summary); err != nil {
+			log.Printf("Sending the sequence summary to chat %d failed: %s", summary.ChatId, err)
+		}
+	}
+	return nil
+}
+
+func sendSequenceSummary(ctx context.Context, summary *model.SequenceSummary) error {
+	mainshock, err := repository.GetEvent(summary.SequenceId)
+	if err != nil {
+		return err
+	}
+	events := []*model.Event{}
+	for _, id := range summary.EventIds {
+		event, err := repository.GetEvent(id)
+		if err != nil {
+			return err
+		}
+		if event != nil {
+			events = append(events, event)
+		}
+	}
+	// Events dropped by the retention cleanup have nothing left to summarise.
+	if mainshock == nil || len(events) == 0 {
+		return repository.DeleteSummaryEvents(summary.ChatId, summary.SequenceId, summary.EventIds)
+	}
+	lang, err := repository.GetLanguage(summary.ChatId)
+	if err != nil {
+		return err
+	}
+	title := i18n.T(lang, "sequence.summary", mainshock.Title)
+	message, err := templates.Render(templates.Digest, lang, &templates.DigestData{
+		Title:  title,
+		Events: events,
+	})
+	if err != nil {
+		return err
+	}
+	replyTo, err := sequenceReply(summary.ChatId, mainshock)
+	if err != nil {
+		return err
+	}
+	if _, err = sendFormatted(ctx, summary.ChatId, message, replyTo); err != nil {
+		// A chat that blocked the bot will never take the summary.
+		if telegram.IsForbidden(err) {
+			return repository.DeleteSummaryEvents(summary.ChatId, summary.SequenceId, summary.EventIds)
+		}
+		return err
+	}
+	recordLastAlert(summary.ChatId, title)
+	// Like sent_alerts, the queue is cleared only once the summary went out.
+	return repository.DeleteSummaryEvents(summary.ChatId, summary.SequenceId, summary.EventIds)
+}
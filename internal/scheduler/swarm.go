@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"alerts/config"
+	"alerts/internal/fetcher"
+	"alerts/internal/geo"
+	"alerts/internal/i18n"
+	"alerts/internal/templates"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"time"
+)
+
+// kmPerDegree converts the swarm radius to the degrees fdsnws-event expects.
+const kmPerDegree = 111.19
+
+// swarmKey records a swarm notice in sent_alerts apart from the alerts for
+// the quakes in it.
+func swarmKey(swarmId string) string {
+	return "swarm#" + swarmId
+}
+
+// detectSwarm returns the swarm event belongs to: SwarmCount or more quakes
+// within SwarmRadius km of it in the SwarmWindow hours up to it, or nil.
+// Aftershocks belong to their sequence instead, so they neither start nor
+// count towards a swarm. A quake near a swarm that is still active extends
+// that swarm rather than starting a new one.
+func detectSwarm(canonicalId string, event *model.Event, mainshock *model.Event) (*model.Swarm, error) {
+	if config.BotConf.SwarmCount <= 0 || mainshock != nil {
+		return nil, nil
+	}
+	occurred := time.UnixMilli(event.Time)
+	from := occurred.Add(-time.Duration(config.BotConf.SwarmWindow) * time.Hour)
+	active, err := repository.GetSwarmsSince(from)
+	if err != nil {
+		return nil, err
+	}
+	swarm := &model.Swarm{Id: canonicalId, Latitude: event.Latitude, Longitude: event.Longitude}
+	for _, candidate := range active {
+		if geo.Distance(candidate.Latitude, candidate.Longitude, event.Latitude, event.Longitude) <= config.BotConf.SwarmRadius {
+			swarm = candidate
+			from = time.UnixMilli(min(from.UnixMilli(), candidate.Start))
+			occurred = time.UnixMilli(max(event.Time, candidate.End))
+			break
+		}
+	}
+	nearby, err := repository.GetEventsBetween(from, occurred)
+	if err != nil {
+		return nil, err
+	}
+	swarm.Count = 0
+	for _, e := range nearby {
+		if e.MainshockId != "" || geo.Distance(swarm.Latitude, swarm.Longitude, e.Latitude, e.Longitude) > config.BotConf.SwarmRadius {
+			continue
+		}
+		if swarm.Count == 0 || e.Magnitude < swarm.MinMagnitude {
+			swarm.MinMagnitude = e.Magnitude
+		}
+		if swarm.Count == 0 {
+			swarm.Start = e.Time
+		}
+		swarm.MaxMagnitude = max(swarm.MaxMagnitude, e.Magnitude)
+		swarm.End = e.Time
+		swarm.Count++
+	}
+	if swarm.Count < config.BotConf.SwarmCount {
+		return nil, nil
+	}
+	return swarm, repository.SaveSwarm(swarm)
+}
+
+// swarmData fills in the swarm template, linking to the quakes of the swarm
+// on the SwarmListURL fdsnws-event service.
+func swarmData(swarm *model.Swarm, place string) *templates.SwarmData {
+	list := &fetcher.FDSNClient{BaseURL: config.BotConf.SwarmListURL, Format: fetcher.FormatText}
+	return &templates.SwarmData{
+		Swarm:  swarm,
+		Place:  place,
+		Radius: config.BotConf.SwarmRadius,
+		ListURL: list.URL(&fetcher.FDSNQuery{
+			StartTime: time.UnixMilli(swarm.Start),
+			// The end time is exclusive to the second.
+			EndTime: time.UnixMilli(swarm.End).Add(time.Second),
+			Circle:  &fetcher.FDSNCircle{Latitude: swarm.Latitude, Longitude: swarm.Longitude, MaxRadius: config.BotConf.SwarmRadius / kmPerDegree},
+		}),
+	}
+}
+
+// sendSwarmNotice tells a user about a swarm once. The daily cleanup of
+// sent_alerts means a swarm still growing the next day is announced again
+// with its updated figures.
+func sendSwarmNotice(ctx context.Context, chatId int64, lang string, swarm *model.Swarm, place string) error {
+	count, err := repository.GetAlertCount(swarmKey(swarm.Id), chatId)
+	if err != nil || count > 0 {
+		return err
+	}
+	message, err := templates.Render(templates.Swarm, lang, swarmData(swarm, place))
+	if err != nil {
+		return err
+	}
+	if _, err = sendFormatted(ctx, chatId, message, 0); err != nil {
+		return err
+	}
+	recordLastAlert(chatId, i18n.T(lang, "swarm.title")+", "+place)
+	return repository.InsertIntoSentAlert(&model.InsertAlertRequest{EarthQuakeId: swarmKey(swarm.Id), ChatId: chatId})
+}
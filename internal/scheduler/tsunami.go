@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"alerts/config"
+	"alerts/internal/country"
+	"alerts/internal/filter"
+	"alerts/internal/geo"
+	"alerts/internal/region"
+	"alerts/internal/telegram"
+	"alerts/internal/templates"
+	"alerts/model"
+	"alerts/repository"
+	"context"
+	"log"
+)
+
+// tsunamiKey records a tsunami warning in sent_alerts apart from the regular
+// alert, so a quake whose tsunami flag is raised after it was first reported
+// still escalates.
+func tsunamiKey(canonicalId string) string {
+	return canonicalId + "#tsunami"
+}
+
+// tsunamiRecipient reports whether a user gets the tsunami warning for event:
+// everyone the regular alert would reach, plus subscribers of coastal
+// countries and watch locations within TsunamiRadius of the epicenter. The
+// distance to a country is measured to its bounding boxes, so the far coasts
+// of large countries are covered too.
+func tsunamiRecipient(countryCode string, regions []*region.Region, watch *model.Location, event *model.Event, address *model.Address) bool {
+	if filter.Covers(countryCode, regions, event, address.CountryCode) {
+		return true
+	}
+	radius := config.BotConf.TsunamiRadius
+	if watch != nil && geo.Distance(watch.Latitude, watch.Longitude, event.Latitude, event.Longitude) <= radius {
+		return true
+	}
+	c, ok := country.Lookup(countryCode)
+	return ok && c.Coastal() && c.Distance(event.Latitude, event.Longitude) <= radius
+}
+
+// sendTsunamiWarning sends the tsunami template once per user and event. It
+// ignores the user's minimum magnitude, and in groups pins the warning. It
+// reports whether a warning went out.
+func sendTsunamiWarning(ctx context.Context, chatId int64, preferences *model.UserPreferences, event *model.Event, address *model.Address, canonicalId string, maps *alertMaps) (bool, error) {
+	count, err := repository.GetAlertCount(tsunamiKey(canonicalId), chatId)
+	if err != nil || count > 0 {
+		return false, err
+	}
+	message, err := templates.Render(templates.Tsunami, preferences.Language, alertData(event, address, preferences.Watch))
+	if err != nil {
+		return false, err
+	}
+	sent, err := sendAlert(ctx, chatId, message, event, canonicalId, preferences.Watch, maps, 0)
+	if err != nil {
+		return false, err
+	}
+	// The regular alert is marked as well so it doesn't follow the warning.
+	for _, id := range []string{tsunamiKey(canonicalId), canonicalId} {
+		if err = repository.InsertIntoSentAlert(&model.InsertAlertRequest{EarthQuakeId: id, ChatId: chatId}); err != nil {
+			return false, err
+		}
+	}
+	recordAlertMessage(canonicalId, chatId, event, sent)
+	// Group and channel ids are negative. Pinning needs admin rights there,
+	// so a failure is only logged.
+	if chatId < 0 && sent != nil {
+		if err = telegram.Bot.PinChatMessage(ctx, &telegram.PinChatMessageParams{ChatID: chatId, MessageID: sent.MessageID}); err != nil {
+			log.Println("Failed to pin the tsunami warning", err.Error())
+		}
+	}
+	if preferences.SendLocation {
+		if err = sendEpicenter(ctx, chatId, event, address, sent); err != nil {
+			log.Println("ERROR SENDING EPICENTER TO TELEGRAM", err.Error())
+		}
+	}
+	return true, nil
+}
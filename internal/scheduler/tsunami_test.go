@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"alerts/config"
+	"alerts/internal/region"
+	"alerts/model"
+	"testing"
+)
+
+func TestTsunamiRecipient(t *testing.T) {
+	config.BotConf = &config.BotConfig{TsunamiRadius: 1000}
+	mediterranean, _ := region.Lookup("mediterranean")
+	// Offshore epicenters have no country code.
+	chile := &model.Event{Latitude: -36.1, Longitude: -73.2}
+	offshore := &model.Address{}
+	tests := []struct {
+		name    string
+		country string
+		regions []*region.Region
+		watch   *model.Location
+		event   *model.Event
+		address *model.Address
+		want    bool
+	}{
+		{"region-only subscriber, offshore quake elsewhere", "", []*region.Region{mediterranean}, nil, chile, offshore, false},
+		{"region-only subscriber, offshore quake inside", "", []*region.Region{mediterranean}, nil, &model.Event{Latitude: 36.5, Longitude: 23.5}, offshore, true},
+		{"global subscriber", "all", nil, nil, chile, offshore, true},
+		{"same country", "cl", nil, nil, chile, &model.Address{CountryCode: "cl"}, true},
+		{"coastal country within reach", "cl", nil, nil, chile, offshore, true},
+		{"far coast of a large country", "us", nil, nil, &model.Event{Latitude: 51.5, Longitude: -175}, offshore, true},
+		{"distant coastal country", "jp", nil, nil, chile, offshore, false},
+		{"landlocked country", "bo", nil, nil, chile, offshore, false},
+		{"watch location within reach", "", nil, &model.Location{Latitude: -33.0, Longitude: -71.6}, chile, offshore, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tsunamiRecipient(tt.country, tt.regions, tt.watch, tt.event, tt.address); got != tt.want {
+				t.Errorf("tsunamiRecipient = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
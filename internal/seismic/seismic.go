@@ -0,0 +1,89 @@
+// Package seismic holds the rules of thumb used to describe how an
+// earthquake is likely to be experienced.
+package seismic
+
+import (
+	"math"
+	"time"
+)
+
+// Depth classes, by hypocentral depth.
+const (
+	Shallow      = "shallow"
+	Intermediate = "intermediate"
+	Deep         = "deep"
+)
+
+// DepthClass classifies a hypocentre: shallow above 70 km, deep from 300 km.
+func DepthClass(depthKm float64) string {
+	switch {
+	case depthKm < 70:
+		return Shallow
+	case depthKm < 300:
+		return Intermediate
+	}
+	return Deep
+}
+
+// Felt likelihoods, from least to most noticeable.
+const (
+	FeltUnlikely = "unlikely"
+	FeltPossible = "possible"
+	FeltLikely   = "likely"
+	FeltStrong   = "strong"
+)
+
+// FeltLikelihood is how noticeable a quake is distanceKm from the epicenter,
+// from the intensity EstimateMMI predicts there: below II it is not felt,
+// up to III only weakly, and from VI shaking is strong.
+func FeltLikelihood(magnitude, depthKm, distanceKm float64) string {
+	intensity := EstimateMMI(magnitude, depthKm, distanceKm)
+	switch {
+	case intensity >= 5.5:
+		return FeltStrong
+	case intensity >= 3.5:
+		return FeltLikely
+	case intensity >= 1.5:
+		return FeltPossible
+	}
+	return FeltUnlikely
+}
+
+// EstimateMMI predicts the Modified Mercalli intensity distanceKm from the
+// epicenter with the active crustal intensity prediction equation of Allen,
+// Wald & Worden (2012) on hypocentral distance:
+//
+//	MMI = 2.085 + 1.428 M - 1.402 ln(sqrt(R² + RM²)) + S
+//	RM  = -0.209 + 2.042 exp(M - 5)
+//	S   = 0.078 ln(R / 50) beyond 50 km, otherwise 0
+//
+// The result is clamped to the I to XII scale.
+func EstimateMMI(magnitude, depthKm, distanceKm float64) float64 {
+	r := math.Hypot(distanceKm, depthKm)
+	rm := -0.209 + 2.042*math.Exp(magnitude-5)
+	intensity := 2.085 + 1.428*magnitude - 1.402*math.Log(math.Sqrt(r*r+rm*rm))
+	if r > 50 {
+		intensity += 0.078 * math.Log(r/50)
+	}
+	return min(max(intensity, 1), 12)
+}
+
+// AftershockWindow is the Gardner–Knopoff (1974) space-time window of a
+// mainshock: later quakes within the returned distance and duration are
+// treated as its aftershocks.
+//
+//	d = 10^(0.1238 M + 0.983) km
+//	t = 10^(0.032 M + 2.7389) days from M6.5, 10^(0.5409 M - 0.547) below
+//
+// The two duration fits cross near M6.46 rather than at M6.5, so the lesser
+// of them is taken to keep the window from shrinking just above M6.5.
+func AftershockWindow(magnitude float64) (float64, time.Duration) {
+	distance := math.Pow(10, 0.1238*magnitude+0.983)
+	days := min(math.Pow(10, 0.5409*magnitude-0.547), math.Pow(10, 0.032*magnitude+2.7389))
+	return distance, time.Duration(days * float64(24*time.Hour))
+}
+
+// Level rounds an intensity to a whole degree of the I to XII scale.
+func Level(intensity float64) int {
+	return min(max(int(math.Round(intensity)), 1), 12)
+}
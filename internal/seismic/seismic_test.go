@@ -0,0 +1,94 @@
+package seismic
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEstimateMMI(t *testing.T) {
+	// Reference values computed separately from the Allen, Wald & Worden
+	// (2012) equation.
+	tests := []struct {
+		name                           string
+		magnitude, depthKm, distanceKm float64
+		want                           float64
+	}{
+		{"M6 at the epicenter", 6, 10, 0, 7.2488},
+		{"M5 nearby", 5, 10, 20, 4.8639},
+		{"M5 at 50 km, no site term yet", 5, 10, 50, 3.7135},
+		{"M7.8 at 100 km", 7.8, 15, 100, 6.7338},
+		{"M4 at 100 km", 4, 10, 100, 1.3880},
+		{"deep M6 above the hypocentre", 6, 600, 0, 1.8783},
+		{"great quake saturates near field", 9.5, 5, 0, 8.3421},
+		{"clamped to I", 3, 10, 300, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateMMI(tt.magnitude, tt.depthKm, tt.distanceKm)
+			if math.Abs(got-tt.want) > 1e-3 {
+				t.Errorf("EstimateMMI(%v, %v, %v) = %.4f, want %.4f", tt.magnitude, tt.depthKm, tt.distanceKm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAftershockWindow(t *testing.T) {
+	// Gardner & Knopoff (1974), table 1. The formulas are a fit to it, worst
+	// at the knee around M6.5, so they are held to within 15%.
+	tests := []struct {
+		magnitude  float64
+		distanceKm float64
+		days       float64
+	}{
+		{5.0, 40, 155},
+		{6.0, 54, 510},
+		{6.5, 61, 790},
+		{7.0, 70, 915},
+		{8.0, 94, 985},
+	}
+	for _, tt := range tests {
+		distance, duration := AftershockWindow(tt.magnitude)
+		if math.Abs(distance-tt.distanceKm) > 0.15*tt.distanceKm {
+			t.Errorf("AftershockWindow(%v) distance = %.1f km, want about %v km", tt.magnitude, distance, tt.distanceKm)
+		}
+		days := duration.Hours() / 24
+		if math.Abs(days-tt.days) > 0.15*tt.days {
+			t.Errorf("AftershockWindow(%v) duration = %.0f days, want about %v days", tt.magnitude, days, tt.days)
+		}
+	}
+}
+
+func TestAftershockWindowGrows(t *testing.T) {
+	previousDistance, previousDuration := 0.0, time.Duration(0)
+	for magnitude := 2.0; magnitude <= 9.0; magnitude += 0.1 {
+		distance, duration := AftershockWindow(magnitude)
+		if distance < previousDistance || duration < previousDuration {
+			t.Fatalf("AftershockWindow(%.1f) = %.1f km, %s shrank from %.1f km, %s", magnitude, distance, duration, previousDistance, previousDuration)
+		}
+		previousDistance, previousDuration = distance, duration
+	}
+}
+
+func TestFeltLikelihood(t *testing.T) {
+	tests := []struct {
+		name                           string
+		magnitude, depthKm, distanceKm float64
+		want                           string
+	}{
+		{"M6 at the epicenter", 6, 10, 0, FeltStrong},
+		{"M7.8 at 100 km", 7.8, 15, 100, FeltStrong},
+		{"M5 nearby", 5, 10, 20, FeltLikely},
+		{"M5 at 50 km", 5, 10, 50, FeltLikely},
+		{"deep M6 above the hypocentre", 6, 600, 0, FeltPossible},
+		{"M4 at 100 km", 4, 10, 100, FeltUnlikely},
+		{"M3 at 300 km", 3, 10, 300, FeltUnlikely},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FeltLikelihood(tt.magnitude, tt.depthKm, tt.distanceKm); got != tt.want {
+				t.Errorf("FeltLikelihood(%v, %v, %v) = %s, want %s", tt.magnitude, tt.depthKm, tt.distanceKm, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package staticmap
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// canvas does the little rasterising the maps need: even-odd polygon fills,
+// one pixel lines and discs, all alpha blended.
+type canvas struct {
+	img *image.RGBA
+}
+
+func newCanvas(width, height int, background color.RGBA) *canvas {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = background.R, background.G, background.B, 255
+	}
+	return &canvas{img: img}
+}
+
+func (cv *canvas) blend(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(cv.img.Rect)) {
+		return
+	}
+	i := cv.img.PixOffset(x, y)
+	a := uint32(c.A)
+	for k, v := range []uint8{c.R, c.G, c.B} {
+		cv.img.Pix[i+k] = uint8((uint32(v)*a + uint32(cv.img.Pix[i+k])*(255-a)) / 255)
+	}
+	cv.img.Pix[i+3] = 255
+}
+
+// fill paints the area enclosed by the rings using scanlines through pixel
+// centres.
+func (cv *canvas) fill(rings [][][2]float64, c color.RGBA) {
+	bounds := cv.img.Rect
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, ring := range rings {
+		for _, p := range ring {
+			minY = min(minY, p[1])
+			maxY = max(maxY, p[1])
+		}
+	}
+	top := max(int(math.Floor(minY)), bounds.Min.Y)
+	bottom := min(int(math.Ceil(maxY)), bounds.Max.Y-1)
+	xs := []float64{}
+	for y := top; y <= bottom; y++ {
+		scan := float64(y) + 0.5
+		xs = xs[:0]
+		for _, ring := range rings {
+			for i := range ring {
+				a, b := ring[i], ring[(i+1)%len(ring)]
+				if (a[1] <= scan) == (b[1] <= scan) {
+					continue
+				}
+				xs = append(xs, a[0]+(scan-a[1])*(b[0]-a[0])/(b[1]-a[1]))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			from := max(int(math.Ceil(xs[i]-0.5)), bounds.Min.X)
+			to := min(int(math.Floor(xs[i+1]-0.5)), bounds.Max.X-1)
+			for x := from; x <= to; x++ {
+				cv.blend(x, y, c)
+			}
+		}
+	}
+}
+
+// stroke draws the closed ring as one pixel lines.
+func (cv *canvas) stroke(ring [][2]float64, c color.RGBA) {
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		cv.line(a[0], a[1], b[0], b[1], c)
+	}
+}
+
+func (cv *canvas) line(x0, y0, x1, y1 float64, c color.RGBA) {
+	size := cv.img.Rect.Size()
+	// Skip segments entirely off one side of the image.
+	if max(x0, x1) < 0 || min(x0, x1) >= float64(size.X) || max(y0, y1) < 0 || min(y0, y1) >= float64(size.Y) {
+		return
+	}
+	steps := int(math.Ceil(max(math.Abs(x1-x0), math.Abs(y1-y0))))
+	if steps == 0 {
+		cv.blend(int(x0), int(y0), c)
+		return
+	}
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		cv.blend(int(math.Floor(x0+(x1-x0)*t)), int(math.Floor(y0+(y1-y0)*t)), c)
+	}
+}
+
+func (cv *canvas) disc(cx, cy, radius float64, c color.RGBA) {
+	for y := int(math.Floor(cy - radius)); y <= int(math.Ceil(cy+radius)); y++ {
+		for x := int(math.Floor(cx - radius)); x <= int(math.Ceil(cx+radius)); x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if dx*dx+dy*dy <= radius*radius {
+				cv.blend(x, y, c)
+			}
+		}
+	}
+}
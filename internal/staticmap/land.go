@@ -0,0 +1,66 @@
+package staticmap
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+)
+
+// land.geojson is a coarse, hand simplified outline of the continents and
+// larger islands, in the same layout as Natural Earth's ne_110m_land so it can
+// be swapped for that file when more detail is wanted. Inland seas are holes.
+//
+//go:embed land.geojson
+var landGeoJSON []byte
+
+// polygon is an outer ring followed by its holes, as [longitude, latitude]
+// pairs. Rings of one polygon are filled with the even-odd rule.
+type polygon struct {
+	rings                          [][][2]float64
+	minLon, maxLon, minLat, maxLat float64
+}
+
+var land []*polygon
+
+func init() {
+	var collection struct {
+		Features []struct {
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(landGeoJSON, &collection); err != nil {
+		log.Fatal("Failed to parse the embedded land outline:", err)
+	}
+	for _, feature := range collection.Features {
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var rings [][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &rings); err != nil {
+				log.Fatal("Failed to parse the embedded land outline:", err)
+			}
+			land = append(land, newPolygon(rings))
+		case "MultiPolygon":
+			var polygons [][][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &polygons); err != nil {
+				log.Fatal("Failed to parse the embedded land outline:", err)
+			}
+			for _, rings := range polygons {
+				land = append(land, newPolygon(rings))
+			}
+		}
+	}
+}
+
+func newPolygon(rings [][][2]float64) *polygon {
+	p := &polygon{rings: rings, minLon: 1000, maxLon: -1000, minLat: 1000, maxLat: -1000}
+	for _, point := range rings[0] {
+		p.minLon = min(p.minLon, point[0])
+		p.maxLon = max(p.maxLon, point[0])
+		p.minLat = min(p.minLat, point[1])
+		p.maxLat = max(p.maxLat, point[1])
+	}
+	return p
+}
@@ -0,0 +1,154 @@
+// Package staticmap renders small PNG maps for alerts without any tile
+// service: land comes from an embedded outline and everything else is drawn
+// on top in an equirectangular projection centred on the epicenter.
+package staticmap
+
+import (
+	"alerts/internal/geo"
+	"bytes"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+const (
+	Width  = 640
+	Height = 480
+	// MaxSpan is the most degrees of latitude a map shows top to bottom.
+	MaxSpan = 60.0
+)
+
+var (
+	Ocean     = color.RGBA{170, 211, 223, 255}
+	Land      = color.RGBA{242, 239, 233, 255}
+	Coast     = color.RGBA{140, 150, 150, 255}
+	Epicenter = color.RGBA{214, 39, 40, 255}
+	Shaking   = color.RGBA{214, 39, 40, 60}
+	Recent    = color.RGBA{255, 127, 14, 220}
+	Watch     = color.RGBA{31, 119, 180, 255}
+	white     = color.RGBA{255, 255, 255, 255}
+)
+
+// Map is a view centred on a point, span degrees of latitude tall.
+type Map struct {
+	latitude  float64
+	longitude float64
+	span      float64
+	shapes    []func(*canvas)
+}
+
+func New(latitude, longitude, span float64) *Map {
+	return &Map{latitude: latitude, longitude: longitude, span: min(span, MaxSpan)}
+}
+
+// Include widens the view so the point is inside it with a margin, unless
+// that would take the map past MaxSpan. It reports whether the point fits.
+func (m *Map) Include(latitude, longitude float64) bool {
+	dy := math.Abs(latitude-m.latitude) * 2.4
+	dx := math.Abs(wrap(longitude-m.longitude)) * m.cos() * 2.4 * Height / Width
+	span := max(m.span, dx, dy)
+	if span > MaxSpan {
+		return false
+	}
+	m.span = span
+	return true
+}
+
+// Contains reports whether the point falls inside the current view.
+func (m *Map) Contains(latitude, longitude float64) bool {
+	x, y := m.project(latitude, longitude)
+	return x >= 0 && x < Width && y >= 0 && y < Height
+}
+
+// Circle draws a translucent circle of radiusKm on the ground.
+func (m *Map) Circle(latitude, longitude, radiusKm float64, c color.RGBA) {
+	m.shapes = append(m.shapes, func(cv *canvas) {
+		ring := make([][2]float64, 0, 73)
+		for bearing := 0.0; bearing <= 360; bearing += 5 {
+			lat, lon := geo.Destination(latitude, longitude, bearing, radiusKm)
+			x, y := m.project(lat, lon)
+			ring = append(ring, [2]float64{x, y})
+		}
+		cv.fill([][][2]float64{ring}, c)
+		cv.stroke(ring, color.RGBA{c.R, c.G, c.B, 255})
+	})
+}
+
+// Marker draws a dot radius pixels wide with a white outline.
+func (m *Map) Marker(latitude, longitude float64, radius float64, c color.RGBA) {
+	m.shapes = append(m.shapes, func(cv *canvas) {
+		x, y := m.project(latitude, longitude)
+		cv.disc(x, y, radius+1.5, white)
+		cv.disc(x, y, radius, c)
+	})
+}
+
+// PNG renders the map.
+func (m *Map) PNG() ([]byte, error) {
+	cv := newCanvas(Width, Height, Ocean)
+	m.drawLand(cv)
+	for _, shape := range m.shapes {
+		shape(cv)
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, cv.img); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (m *Map) drawLand(cv *canvas) {
+	// Outlines may run past ±180°, so each polygon is also tried one turn
+	// east and west of where it is defined.
+	for _, p := range land {
+		for _, offset := range []float64{-360, 0, 360} {
+			if !m.overlaps(p, offset) {
+				continue
+			}
+			rings := make([][][2]float64, len(p.rings))
+			for i, ring := range p.rings {
+				rings[i] = make([][2]float64, len(ring))
+				for j, point := range ring {
+					x, y := m.projectRaw(point[1], point[0]+offset)
+					rings[i][j] = [2]float64{x, y}
+				}
+			}
+			cv.fill(rings, Land)
+			for _, ring := range rings {
+				cv.stroke(ring, Coast)
+			}
+		}
+	}
+}
+
+func (m *Map) overlaps(p *polygon, offset float64) bool {
+	x0, y0 := m.projectRaw(p.maxLat, p.minLon+offset)
+	x1, y1 := m.projectRaw(p.minLat, p.maxLon+offset)
+	return x1 >= 0 && x0 < Width && y1 >= 0 && y0 < Height
+}
+
+func (m *Map) scale() float64 {
+	return Height / m.span
+}
+
+// cos keeps shapes roughly true near the centre; it is floored so polar maps
+// don't degenerate.
+func (m *Map) cos() float64 {
+	return max(math.Cos(m.latitude*math.Pi/180), 0.2)
+}
+
+// project places a point, taking the shorter way round the antimeridian.
+func (m *Map) project(latitude, longitude float64) (float64, float64) {
+	return m.projectRaw(latitude, m.longitude+wrap(longitude-m.longitude))
+}
+
+func (m *Map) projectRaw(latitude, longitude float64) (float64, float64) {
+	x := Width/2 + (longitude-m.longitude)*m.cos()*m.scale()
+	y := Height/2 - (latitude-m.latitude)*m.scale()
+	return x, y
+}
+
+// wrap brings a longitude difference into [-180, 180).
+func wrap(degrees float64) float64 {
+	return math.Mod(math.Mod(degrees+180, 360)+360, 360) - 180
+}
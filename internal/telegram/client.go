@@ -0,0 +1,208 @@
+package telegram
+
+import (
+	"alerts/config"
+	"alerts/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Bot is the client used by the rest of the application, set up by Init.
+var Bot *Client
+
+// Client talks to the Telegram Bot API. It is safe for concurrent use and
+// shares one connection pool between all calls.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func NewClient(domain, token string) *Client {
+	return &Client{
+		baseURL: domain + token,
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				MaxIdleConns:        20,
+				MaxIdleConnsPerHost: 20,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+func Init(conf *config.BotConfig) *Client {
+	Bot = NewClient(conf.TelegramDomain, conf.BotToken)
+	return Bot
+}
+
+func (c *Client) SendMessage(ctx context.Context, params *SendMessageParams) (*model.Message, error) {
+	message := new(model.Message)
+	if err := c.call(ctx, "sendMessage", params, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (c *Client) EditMessageText(ctx context.Context, params *EditMessageTextParams) error {
+	return c.call(ctx, "editMessageText", params, nil)
+}
+
+func (c *Client) AnswerCallbackQuery(ctx context.Context, params *AnswerCallbackQueryParams) error {
+	return c.call(ctx, "answerCallbackQuery", params, nil)
+}
+
+func (c *Client) PinChatMessage(ctx context.Context, params *PinChatMessageParams) error {
+	return c.call(ctx, "pinChatMessage", params, nil)
+}
+
+func (c *Client) SendLocation(ctx context.Context, params *SendLocationParams) (*model.Message, error) {
+	message := new(model.Message)
+	if err := c.call(ctx, "sendLocation", params, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (c *Client) SendVenue(ctx context.Context, params *SendVenueParams) (*model.Message, error) {
+	message := new(model.Message)
+	if err := c.call(ctx, "sendVenue", params, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (c *Client) SendPhoto(ctx context.Context, params *SendPhotoParams) (*model.Message, error) {
+	message := new(model.Message)
+	if params.PhotoData == nil {
+		body := map[string]any{
+			"chat_id": params.ChatID,
+			"photo":   params.Photo,
+		}
+		if params.Caption != "" {
+			body["caption"] = params.Caption
+		}
+		if params.ParseMode != "" {
+			body["parse_mode"] = params.ParseMode
+		}
+		if params.ReplyToMessageID != 0 {
+			body["reply_to_message_id"] = params.ReplyToMessageID
+		}
+		if params.AllowSendingWithoutReply {
+			body["allow_sending_without_reply"] = true
+		}
+		if err := c.call(ctx, "sendPhoto", body, message); err != nil {
+			return nil, err
+		}
+		return message, nil
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("chat_id", strconv.FormatInt(params.ChatID, 10))
+	if params.Caption != "" {
+		writer.WriteField("caption", params.Caption)
+	}
+	if params.ParseMode != "" {
+		writer.WriteField("parse_mode", params.ParseMode)
+	}
+	if params.ReplyToMessageID != 0 {
+		writer.WriteField("reply_to_message_id", strconv.FormatInt(params.ReplyToMessageID, 10))
+	}
+	if params.AllowSendingWithoutReply {
+		writer.WriteField("allow_sending_without_reply", "true")
+	}
+	fileName := params.FileName
+	if fileName == "" {
+		fileName = "photo.png"
+	}
+	part, err := writer.CreateFormFile("photo", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(params.PhotoData); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	if err = c.do(ctx, "sendPhoto", writer.FormDataContentType(), &body, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (c *Client) GetUpdates(ctx context.Context, params *GetUpdatesParams) ([]*model.Result, error) {
+	results := []*model.Result{}
+	if err := c.call(ctx, "getUpdates", params, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (c *Client) SetWebhook(ctx context.Context, params *SetWebhookParams) error {
+	return c.call(ctx, "setWebhook", params, nil)
+}
+
+func (c *Client) GetChatMember(ctx context.Context, chatId, userId int64) (*ChatMember, error) {
+	member := new(ChatMember)
+	if err := c.call(ctx, "getChatMember", &GetChatMemberParams{ChatID: chatId, UserID: userId}, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// call sends params as JSON to method and decodes the result into result,
+// which may be nil when the caller doesn't need it.
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	return c.do(ctx, method, "application/json", bytes.NewReader(body), result)
+}
+
+func (c *Client) do(ctx context.Context, method, contentType string, body io.Reader, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+method, body)
+	if err != nil {
+		return redact(method, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "earthquake-alert-bot/1.0")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return redact(method, err)
+	}
+	defer resp.Body.Close()
+
+	response := new(apiResponse)
+	if err = json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("telegram %s: status %d: %w", method, resp.StatusCode, err)
+	}
+	if !response.Ok {
+		apiErr := &APIError{Method: method, Code: response.ErrorCode, Description: response.Description}
+		if apiErr.Code == 0 {
+			apiErr.Code = resp.StatusCode
+		}
+		if response.Parameters != nil {
+			apiErr.RetryAfter = response.Parameters.RetryAfter
+			apiErr.MigrateToChatID = response.Parameters.MigrateToChatID
+		}
+		return apiErr
+	}
+	if result != nil {
+		if err = json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("telegram %s: %w", method, err)
+		}
+	}
+	return nil
+}
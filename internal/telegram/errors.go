@@ -0,0 +1,55 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIError is an error reported by the Bot API itself, as opposed to a
+// transport failure.
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+	// RetryAfter is set on 429 responses to the number of seconds to wait.
+	RetryAfter int
+	// MigrateToChatID is set when a group has been upgraded to a supergroup.
+	MigrateToChatID int64
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+func IsForbidden(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden
+}
+
+func IsTooManyRequests(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
+
+// RetryAfter returns how long a 429 asked to wait before retrying, or zero
+// if err isn't one.
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+		return 0
+	}
+	return time.Duration(apiErr.RetryAfter) * time.Second
+}
+
+// redact strips the request URL, which carries the bot token, from transport
+// errors so they are safe to log.
+func redact(method string, err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("telegram %s: %s: %w", method, urlErr.Op, urlErr.Err)
+	}
+	return fmt.Errorf("telegram %s: %w", method, err)
+}
@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"alerts/model"
+	"encoding/json"
+)
+
+type SendMessageParams struct {
+	ChatID                int64                       `json:"chat_id"`
+	Text                  string                      `json:"text"`
+	ParseMode             string                      `json:"parse_mode,omitempty"`
+	ReplyToMessageID      int64                       `json:"reply_to_message_id,omitempty"`
+	DisableWebPagePreview bool                        `json:"disable_web_page_preview,omitempty"`
+	ReplyMarkup           *model.InlineKeyBoardMarkup `json:"reply_markup,omitempty"`
+	// AllowSendingWithoutReply still sends the message if the one it replies
+	// to has been deleted.
+	AllowSendingWithoutReply bool `json:"allow_sending_without_reply,omitempty"`
+}
+
+type EditMessageTextParams struct {
+	ChatID      int64                       `json:"chat_id"`
+	MessageID   int64                       `json:"message_id"`
+	Text        string                      `json:"text"`
+	ParseMode   string                      `json:"parse_mode,omitempty"`
+	ReplyMarkup *model.InlineKeyBoardMarkup `json:"reply_markup,omitempty"`
+}
+
+type AnswerCallbackQueryParams struct {
+	CallbackQueryID string `json:"callback_query_id"`
+	Text            string `json:"text,omitempty"`
+	ShowAlert       bool   `json:"show_alert,omitempty"`
+}
+
+type PinChatMessageParams struct {
+	ChatID              int64 `json:"chat_id"`
+	MessageID           int64 `json:"message_id"`
+	DisableNotification bool  `json:"disable_notification,omitempty"`
+}
+
+type SendLocationParams struct {
+	ChatID           int64   `json:"chat_id"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	ReplyToMessageID int64   `json:"reply_to_message_id,omitempty"`
+}
+
+// SendVenueParams pins a named place; Telegram requires both Title and Address.
+type SendVenueParams struct {
+	ChatID           int64   `json:"chat_id"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	Title            string  `json:"title"`
+	Address          string  `json:"address"`
+	ReplyToMessageID int64   `json:"reply_to_message_id,omitempty"`
+}
+
+// SendPhotoParams sends either an existing photo (file_id or URL) in Photo,
+// or uploads the raw image bytes in PhotoData.
+type SendPhotoParams struct {
+	ChatID                   int64
+	Photo                    string
+	PhotoData                []byte
+	FileName                 string
+	Caption                  string
+	ParseMode                string
+	ReplyToMessageID         int64
+	AllowSendingWithoutReply bool
+}
+
+type GetUpdatesParams struct {
+	Offset  int64 `json:"offset,omitempty"`
+	Limit   int   `json:"limit,omitempty"`
+	Timeout int   `json:"timeout,omitempty"`
+}
+
+type SetWebhookParams struct {
+	URL            string   `json:"url"`
+	SecretToken    string   `json:"secret_token,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
+type GetChatMemberParams struct {
+	ChatID int64 `json:"chat_id"`
+	UserID int64 `json:"user_id"`
+}
+
+type ChatMember struct {
+	Status string      `json:"status"`
+	User   *model.User `json:"user"`
+}
+
+type responseParameters struct {
+	MigrateToChatID int64 `json:"migrate_to_chat_id"`
+	RetryAfter      int   `json:"retry_after"`
+}
+
+type apiResponse struct {
+	Ok          bool                `json:"ok"`
+	Result      json.RawMessage     `json:"result"`
+	ErrorCode   int                 `json:"error_code"`
+	Description string              `json:"description"`
+	Parameters  *responseParameters `json:"parameters"`
+}
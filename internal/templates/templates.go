@@ -0,0 +1,168 @@
+package templates
+
+import (
+	"alerts/internal/i18n"
+	"alerts/internal/markup"
+	"alerts/internal/seismic"
+	"alerts/model"
+	"bytes"
+	"embed"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	Alert    = "alert.tmpl"
+	Revision = "revision.tmpl"
+	Digest   = "digest.tmpl"
+	Keyboard = "keyboard.tmpl"
+	Tsunami  = "tsunami.tmpl"
+	Swarm    = "swarm.tmpl"
+)
+
+//go:embed defaults/*.tmpl
+var defaults embed.FS
+
+var names = []string{Alert, Revision, Digest, Keyboard, Tsunami, Swarm}
+
+var (
+	mu        sync.RWMutex
+	templates = map[string]*template.Template{}
+	mode      = markup.MarkdownV2
+)
+
+type AlertData struct {
+	Event   *model.Event
+	Address *model.Address
+	MapURL  string
+	// Felt is a seismic.FeltLikelihood near the epicenter. With a watch
+	// location, Intensity is the estimated MMI Distance km away instead.
+	Felt      string
+	Distance  float64
+	Intensity float64
+	// Mainshock is set when the event is an aftershock.
+	Mainshock *model.Event
+}
+
+type RevisionData struct {
+	Event             *model.Event
+	PreviousMagnitude float64
+	MapURL            string
+}
+
+// SwarmData describes a swarm of Swarm.Count quakes within Radius km of
+// Place; ListURL lists them.
+type SwarmData struct {
+	Swarm   *model.Swarm
+	Place   string
+	Radius  float64
+	ListURL string
+}
+
+type DigestData struct {
+	Title  string
+	Events []*model.Event
+}
+
+// escape, bold, italic, link and code format text for the configured parse
+// mode, so the same template works for MarkdownV2 and HTML.
+var funcs = template.FuncMap{
+	"escape": markup.MarkdownV2.Escape,
+	"bold":   markup.MarkdownV2.Bold,
+	"italic": markup.MarkdownV2.Italic,
+	"link":   markup.MarkdownV2.Link,
+	"code":   markup.MarkdownV2.Code,
+	"num": func(value float64, decimals int) string {
+		return fmt.Sprintf("%.*f", decimals, value)
+	},
+	"depthclass": seismic.DepthClass,
+	"mmi":        seismic.Level,
+	// roman writes an intensity such as MMI or CDI as a Roman numeral.
+	"roman": func(intensity float64) string {
+		numerals := []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII", "IX", "X", "XI", "XII"}
+		return numerals[seismic.Level(intensity)-1]
+	},
+	// t, tn and time are bound to the reader's language in Render.
+	"t":    func(key string, args ...any) string { return key },
+	"tn":   func(key string, n int, args ...any) string { return key },
+	"time": func(millis int64) string { return "" },
+}
+
+func init() {
+	for _, name := range names {
+		templates[name] = template.Must(template.New(name).Funcs(funcs).ParseFS(defaults, "defaults/"+name))
+	}
+}
+
+// Init sets the parse mode templates render for and replaces the embedded
+// defaults with any template of the same name found in dir. A template that
+// fails to parse is reported and the default kept.
+func Init(dir string, parseMode markup.Mode) error {
+	mu.Lock()
+	defer mu.Unlock()
+	mode = parseMode
+	if dir == "" {
+		return nil
+	}
+	var errs []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		t, err := template.New(name).Funcs(funcs).ParseFiles(path)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		log.Println("Using template override", path)
+		templates[name] = t
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid templates: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Mode is the parse mode rendered messages must be sent with.
+func Mode() markup.Mode {
+	mu.RLock()
+	defer mu.RUnlock()
+	return mode
+}
+
+// Render executes the named template in lang, trimming surrounding whitespace.
+func Render(name, lang string, data any) (string, error) {
+	mu.RLock()
+	t, ok := templates[name]
+	m := mode
+	mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown template %q", name)
+	}
+	t, err := t.Clone()
+	if err != nil {
+		return "", err
+	}
+	t.Funcs(template.FuncMap{
+		"t":      func(key string, args ...any) string { return i18n.T(lang, key, args...) },
+		"tn":     func(key string, n int, args ...any) string { return i18n.N(lang, key, n, args...) },
+		"time":   func(millis int64) string { return i18n.Date(lang, "date.long", time.UnixMilli(millis).UTC()) },
+		"escape": m.Escape,
+		"bold":   m.Bold,
+		"italic": m.Italic,
+		"link":   m.Link,
+		"code":   m.Code,
+	})
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
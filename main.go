@@ -3,10 +3,20 @@ package main
 import (
 	"alerts/config"
 	"alerts/cronjob"
+	"alerts/internal/health"
+	"alerts/internal/leader"
+	"alerts/internal/markup"
 	"alerts/internal/scheduler"
+	"alerts/internal/telegram"
+	"alerts/internal/templates"
 	"alerts/repository"
+	"context"
 	"log"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
 	env "github.com/caarlos0/env/v10"
 	"github.com/joho/godotenv"
@@ -14,6 +24,8 @@ import (
 
 func main() {
 	var wg sync.WaitGroup
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	err := godotenv.Load("local.env")
 	if err != nil {
 		log.Println("Failed to load ENV file")
@@ -26,10 +38,49 @@ func main() {
 	if err != nil {
 		log.Println("Cannot establish connection", err.Error())
 	}
-	cronjob.ScheduleCleanupJob()
+	telegram.Init(config.BotConf)
+	parseMode, err := markup.ParseMode(config.BotConf.ParseMode)
+	if err != nil {
+		log.Println("Falling back to MarkdownV2:", err)
+	}
+	if err = templates.Init(config.BotConf.TemplateDir, parseMode); err != nil {
+		log.Println("Falling back to default templates:", err)
+	}
+	health.FailureThreshold = config.BotConf.HealthThreshold
+	if config.BotConf.HealthAddr != "" {
+		health.Serve(ctx, config.BotConf.HealthAddr)
+	}
+	cleanup := cronjob.ScheduleCleanupJob()
+	wg.Add(1)
+	go leader.Elect(ctx, &wg)
 	wg.Add(1)
-	go scheduler.PollingAlerts(&wg)
+	go scheduler.PollingAlerts(ctx, &wg)
 	wg.Add(1)
-	go scheduler.PollAddUser(&wg)
-	wg.Wait()
+	go scheduler.PollAddUser(ctx, &wg)
+
+	<-ctx.Done()
+	log.Println("Shutting down, waiting for in-flight work to finish")
+	shutdown(&wg, cleanup.Stop(), time.Duration(config.BotConf.ShutdownTimeout)*time.Second)
+	if repository.DB != nil {
+		if err = repository.DB.Close(); err != nil {
+			log.Println("Error closing the database", err.Error())
+		}
+	}
+	log.Println("Shutdown complete")
+}
+
+// shutdown waits for the polling goroutines and the running cron jobs, giving
+// up after timeout.
+func shutdown(wg *sync.WaitGroup, cronCtx context.Context, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		<-cronCtx.Done()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Println("Shutdown timed out, exiting with work still in flight")
+	}
 }
@@ -1,8 +1,14 @@
 package model
 
 type Data struct {
+	Metadata *Metadata  `json:"metadata"`
 	Features []*Feature `json:"features"`
 }
+
+type Metadata struct {
+	Generated int64 `json:"generated"`
+}
+
 type Feature struct {
 	Id         string      `json:"id"`
 	Geo        *Geometry   `json:"geometry"`
@@ -18,6 +24,20 @@ type Properties struct {
 	Place     string  `json:"place"`
 	Tsunami   int     `json:"tsunami"`
 	Time      int64   `json:"time"`
+	Updated   int64   `json:"updated"`
+	Ids       string  `json:"ids"`
+	Sources   string  `json:"sources"`
+	// Alert is the PAGER level: green, yellow, orange or red.
+	Alert        string  `json:"alert"`
+	MMI          float64 `json:"mmi"`
+	CDI          float64 `json:"cdi"`
+	Felt         int     `json:"felt"`
+	Significance int     `json:"sig"`
+	// Status is automatic, reviewed or deleted.
+	Status  string `json:"status"`
+	MagType string `json:"magType"`
+	Net     string `json:"net"`
+	URL     string `json:"url"`
 }
 
 type ChatUsers struct {
@@ -31,13 +51,55 @@ type Result struct {
 }
 
 type Message struct {
-	MessageID int64 `json:"message_id"`
-	Chat      *Chat `json:"chat"`
+	MessageID int64        `json:"message_id"`
+	From      *User        `json:"from,omitempty"`
+	Chat      *Chat        `json:"chat"`
+	Text      string       `json:"text,omitempty"`
+	Location  *Location    `json:"location,omitempty"`
+	Photo     []*PhotoSize `json:"photo,omitempty"`
+	// ReplyToMessage is the message this one answers, without its own reply.
+	ReplyToMessage *Message `json:"reply_to_message,omitempty"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// PhotoSize is one resolution of a sent photo; the largest comes last.
+type PhotoSize struct {
+	FileId string `json:"file_id"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
 }
 
 type Chat struct {
 	Id       int64  `json:"id"`
 	UserName string `json:"username"`
+	// Type is "private", "group", "supergroup" or "channel".
+	Type string `json:"type"`
+}
+
+// Swarm is a cluster of quakes close in space and time with no dominant
+// mainshock. Start and End are the origin times of its first and last quake
+// in milliseconds.
+type Swarm struct {
+	Id           string
+	Latitude     float64
+	Longitude    float64
+	Count        int
+	MinMagnitude float64
+	MaxMagnitude float64
+	Start        int64
+	End          int64
+}
+
+// SequenceSummary is the aftershocks of one sequence waiting to be
+// summarised for a chat.
+type SequenceSummary struct {
+	ChatId     int64
+	SequenceId string
+	EventIds   []string
 }
 
 type InsertAlertRequest struct {
@@ -46,14 +108,25 @@ type InsertAlertRequest struct {
 }
 
 type InsertBotUser struct {
-	ChatId   int64
-	UserName string
+	ChatId       int64
+	UserName     string
+	LanguageCode string
 }
 
-type TelegramMessage struct {
-	ChatID    int64  `json:"chat_id"`
-	Text      string `json:"text"`
-	ParseMode string `json:"parse_mode"`
+type UserPreferences struct {
+	MinMagnitude float64
+	Language     string
+	SendLocation bool
+	// Watch is the location the user shared to follow, if any.
+	Watch *Location
+	// MinAlert is the lowest PAGER level to alert on; empty means any.
+	MinAlert     string
+	ReviewedOnly bool
+	// MaxDepth in km drops deeper events; 0 means no limit.
+	MaxDepth float64
+	// MinIntensity drops events estimated to shake the watch location less
+	// than this MMI; 0 means no limit.
+	MinIntensity float64
 }
 
 type GeoResponse struct {
@@ -77,17 +150,112 @@ type InlineKeyBoardMarkup struct {
 	InlineKeyBoard [][]InlineKeyBoardButton `json:"inline_keyboard"`
 }
 
-type TelegramMessageWithKeyboard struct {
-	ChatID      int64                `json:"chat_id"`
-	Text        string               `json:"text"`
-	ReplyMarkup InlineKeyBoardMarkup `json:"reply_markup"`
-}
-
 type CallbackQuery struct {
-	From *User  `json:"from"`
-	Data string `json:"data"`
+	Id      string   `json:"id"`
+	From    *User    `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data"`
 }
 
 type User struct {
-	Id int64 `json:"id"`
+	Id           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+type Event struct {
+	Id        string
+	Source    string
+	Title     string
+	Place     string
+	Magnitude float64
+	Latitude  float64
+	Longitude float64
+	Depth     float64
+	Tsunami   int
+	Time      int64
+	Updated   int64
+	// Ids holds every id the event is known by, including Id.
+	Ids []string
+	// The USGS impact and review details below are only set for USGS
+	// GeoJSON; zero means not reported.
+	Alert        string
+	MMI          float64
+	CDI          float64
+	Felt         int
+	Significance int
+	Status       string
+	MagType      string
+	Network      string
+	URL          string
+	// MainshockId is the canonical id of the mainshock of a stored
+	// aftershock, empty for anything else.
+	MainshockId string
+	// CountryCode is where Nominatim places the epicenter, empty offshore.
+	CountryCode string
+}
+
+type EMSCData struct {
+	Features []*EMSCFeature `json:"features"`
+}
+
+type EMSCFeature struct {
+	Id         string          `json:"id"`
+	Properties *EMSCProperties `json:"properties"`
+}
+
+type EMSCProperties struct {
+	SourceId    string  `json:"source_id"`
+	Time        string  `json:"time"`
+	Latitude    float64 `json:"lat"`
+	Longitude   float64 `json:"lon"`
+	Depth       float64 `json:"depth"`
+	Magnitude   float64 `json:"mag"`
+	MagType     string  `json:"magtype"`
+	FlynnRegion string  `json:"flynn_region"`
+	LastUpdate  string  `json:"lastupdate"`
+}
+
+type QuakeML struct {
+	EventParameters *EventParameters `xml:"eventParameters"`
+}
+
+type EventParameters struct {
+	Events []*QuakeMLEvent `xml:"event"`
+}
+
+type QuakeMLEvent struct {
+	PublicID             string              `xml:"publicID,attr"`
+	Descriptions         []*QuakeMLText      `xml:"description"`
+	Origins              []*QuakeMLOrigin    `xml:"origin"`
+	Magnitudes           []*QuakeMLMagnitude `xml:"magnitude"`
+	PreferredOriginID    string              `xml:"preferredOriginID"`
+	PreferredMagnitudeID string              `xml:"preferredMagnitudeID"`
+}
+
+type QuakeMLText struct {
+	Type string `xml:"type"`
+	Text string `xml:"text"`
+}
+
+type QuakeMLOrigin struct {
+	PublicID  string        `xml:"publicID,attr"`
+	Time      QuakeMLString `xml:"time"`
+	Latitude  QuakeMLValue  `xml:"latitude"`
+	Longitude QuakeMLValue  `xml:"longitude"`
+	Depth     QuakeMLValue  `xml:"depth"`
+}
+
+type QuakeMLMagnitude struct {
+	PublicID string       `xml:"publicID,attr"`
+	Mag      QuakeMLValue `xml:"mag"`
+	Type     string       `xml:"type"`
+}
+
+type QuakeMLValue struct {
+	Value float64 `xml:"value"`
+}
+
+type QuakeMLString struct {
+	Value string `xml:"value"`
 }
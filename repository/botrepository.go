@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -29,17 +30,21 @@ func DBInit(config *config.DBConfig) (*sql.DB, error) {
 	err := DB.Ping()
 	if err == nil {
 		log.Println("Connection established successfully")
+		err = Migrate()
 	}
 	return DB, err
 }
 
 func InsertIntoTelegramBot(user *model.InsertBotUser) error {
-	query := `insert into telegramuser (id, username) values ($1, $2) on conflict (id) do nothing`
+	// language_code is refreshed on every message so the default language
+	// follows the user's Telegram client.
+	query := `insert into telegramuser (id, username, language_code) values ($1, $2, $3)
+		on conflict (id) do update set language_code = coalesce(excluded.language_code, telegramuser.language_code)`
 	if DB == nil {
 		log.Println("DB is nil what the heck")
 		return errors.New("DB is nil")
 	}
-	_, err := DB.Exec(query, user.ChatId, user.UserName)
+	_, err := DB.Exec(query, user.ChatId, user.UserName, sql.NullString{String: user.LanguageCode, Valid: user.LanguageCode != ""})
 	if err != nil {
 		log.Println("error inserting the data", err.Error())
 		return err
@@ -57,6 +62,161 @@ func UpdateCountryPreference(country string, id int64) error {
 	return err
 }
 
+func UpdateMinMagnitude(magnitude float64, id int64) error {
+	query := `update telegramuser set min_magnitude = $1 where id = $2`
+	_, err := DB.Exec(query, magnitude, id)
+	if err != nil {
+		log.Println("error updating minimum magnitude", err.Error())
+	}
+	return err
+}
+
+// Unsubscribe drops the chat's country and every region subscription together,
+// as either one alone still gets it alerts. keyboardsent stays set so the
+// country keyboard isn't pushed at it again.
+func Unsubscribe(id int64) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec(`update telegramuser set country = null, keyboardsent = true where id = $1`, id); err != nil {
+		log.Println("error unsubscribing user", err.Error())
+		return err
+	}
+	if _, err = tx.Exec(`delete from user_regions where chat_id = $1`, id); err != nil {
+		log.Println("error clearing the user regions", err.Error())
+		return err
+	}
+	return tx.Commit()
+}
+
+func GetUserPreferences(chatId int64) (*model.UserPreferences, error) {
+	preferences := new(model.UserPreferences)
+	var watchLatitude, watchLongitude sql.NullFloat64
+	query := `select min_magnitude, coalesce(language, language_code, ''), send_location, watch_latitude, watch_longitude,
+		coalesce(min_alert, ''), reviewed_only, coalesce(max_depth, 0), coalesce(min_intensity, 0)
+		from telegramuser where id = $1`
+	err := DB.QueryRow(query, chatId).Scan(&preferences.MinMagnitude, &preferences.Language, &preferences.SendLocation, &watchLatitude, &watchLongitude,
+		&preferences.MinAlert, &preferences.ReviewedOnly, &preferences.MaxDepth, &preferences.MinIntensity)
+	if watchLatitude.Valid && watchLongitude.Valid {
+		preferences.Watch = &model.Location{Latitude: watchLatitude.Float64, Longitude: watchLongitude.Float64}
+	}
+	return preferences, err
+}
+
+// UpdateLastAlert remembers the last alert sent to a chat for /status.
+func UpdateLastAlert(chatId int64, title string) error {
+	query := `update telegramuser set last_alert_title = $1, last_alert_at = now() where id = $2`
+	_, err := DB.Exec(query, title, chatId)
+	if err != nil {
+		log.Println("error updating the last alert", err.Error())
+	}
+	return err
+}
+
+// GetLastAlert returns the title and time of the last alert sent to a chat,
+// or a zero time if it never got one.
+func GetLastAlert(chatId int64) (string, time.Time, error) {
+	var title sql.NullString
+	var sentAt sql.NullTime
+	query := `select last_alert_title, last_alert_at from telegramuser where id = $1`
+	err := DB.QueryRow(query, chatId).Scan(&title, &sentAt)
+	if err == sql.ErrNoRows {
+		return "", time.Time{}, nil
+	}
+	return title.String, sentAt.Time, err
+}
+
+// UpdateMinAlert sets the lowest PAGER level to alert on; an empty level
+// clears the filter.
+func UpdateMinAlert(level string, id int64) error {
+	query := `update telegramuser set min_alert = nullif($1, '') where id = $2`
+	_, err := DB.Exec(query, level, id)
+	if err != nil {
+		log.Println("error updating minimum alert level", err.Error())
+	}
+	return err
+}
+
+func UpdateReviewedOnly(enabled bool, id int64) error {
+	query := `update telegramuser set reviewed_only = $1 where id = $2`
+	_, err := DB.Exec(query, enabled, id)
+	if err != nil {
+		log.Println("error updating reviewed only", err.Error())
+	}
+	return err
+}
+
+// UpdateMaxDepth sets the deepest event in km to alert on; 0 clears the limit.
+func UpdateMaxDepth(depth float64, id int64) error {
+	query := `update telegramuser set max_depth = nullif($1::double precision, 0) where id = $2`
+	_, err := DB.Exec(query, depth, id)
+	if err != nil {
+		log.Println("error updating maximum depth", err.Error())
+	}
+	return err
+}
+
+// UpdateMinIntensity sets the lowest estimated MMI at the watch location to
+// alert on; 0 clears the limit.
+func UpdateMinIntensity(intensity float64, id int64) error {
+	query := `update telegramuser set min_intensity = nullif($1::double precision, 0) where id = $2`
+	_, err := DB.Exec(query, intensity, id)
+	if err != nil {
+		log.Println("error updating minimum intensity", err.Error())
+	}
+	return err
+}
+
+func UpdateWatchLocation(location *model.Location, id int64) error {
+	query := `update telegramuser set watch_latitude = $1, watch_longitude = $2 where id = $3`
+	_, err := DB.Exec(query, location.Latitude, location.Longitude, id)
+	if err != nil {
+		log.Println("error updating watch location", err.Error())
+	}
+	return err
+}
+
+func ClearWatchLocation(id int64) error {
+	query := `update telegramuser set watch_latitude = null, watch_longitude = null where id = $1`
+	_, err := DB.Exec(query, id)
+	if err != nil {
+		log.Println("error clearing watch location", err.Error())
+	}
+	return err
+}
+
+func UpdateSendLocation(enabled bool, id int64) error {
+	query := `update telegramuser set send_location = $1 where id = $2`
+	_, err := DB.Exec(query, enabled, id)
+	if err != nil {
+		log.Println("error updating send location", err.Error())
+	}
+	return err
+}
+
+func UpdateLanguage(language string, id int64) error {
+	query := `update telegramuser set language = $1 where id = $2`
+	_, err := DB.Exec(query, language, id)
+	if err != nil {
+		log.Println("error updating language", err.Error())
+	}
+	return err
+}
+
+// GetLanguage returns the language the user picked with /language, or the
+// last language_code Telegram sent, or an empty string.
+func GetLanguage(chatId int64) (string, error) {
+	var language string
+	query := `select coalesce(language, language_code, '') from telegramuser where id = $1`
+	err := DB.QueryRow(query, chatId).Scan(&language)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return language, err
+}
+
 func GetFromTelegramBot() []*model.InsertBotUser {
 	botUsers := []*model.InsertBotUser{}
 	query := "select id, username from telegramuser"
@@ -137,9 +297,13 @@ func SetKeyBoardSent(chatId int64) error {
 	return err
 }
 
-func ClearAllUpdatesForADay() error {
-	query := `DELETE FROM sent_alerts WHERE inserted_at < NOW() - INTERVAL '1 day'`
-	_, err := DB.Exec(query)
+// ClearAllUpdatesForADay forgets alerts older than a day, except the message
+// ids of possible mainshocks, which aftershocks keep replying to for as long
+// as the event is retained.
+func ClearAllUpdatesForADay(sequenceMagnitude float64) error {
+	query := `DELETE FROM sent_alerts WHERE inserted_at < NOW() - INTERVAL '1 day'
+		AND (message_id IS NULL OR earthquake_id NOT IN (SELECT id FROM events WHERE magnitude >= $1))`
+	_, err := DB.Exec(query, sequenceMagnitude)
 	if err != nil {
 		log.Println("Error clearing notification for last 24 hours", err)
 	}
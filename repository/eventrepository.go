@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"alerts/model"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetCanonicalEventId returns the canonical event any of the ids is an alias of,
+// or an empty string if none of them has been seen before.
+func GetCanonicalEventId(ids []string) (string, error) {
+	var eventId string
+	query := `select event_id from event_alias where alias_id = any($1) limit 1`
+	err := DB.QueryRow(query, pq.Array(ids)).Scan(&eventId)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return eventId, err
+}
+
+// eventColumns is the select list scanEvent reads.
+const eventColumns = `id, source, coalesce(title, ''), coalesce(place, ''), coalesce(magnitude, 0), latitude, longitude, coalesce(depth, 0), tsunami, event_time,
+	coalesce(alert, ''), coalesce(mmi, 0), coalesce(cdi, 0), coalesce(felt, 0), coalesce(sig, 0), coalesce(status, ''), coalesce(mag_type, ''), coalesce(net, ''), coalesce(url, ''), coalesce(mainshock_id, ''), coalesce(country_code, '')`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEvent(row scanner) (*model.Event, error) {
+	var eventTime time.Time
+	event := new(model.Event)
+	err := row.Scan(&event.Id, &event.Source, &event.Title, &event.Place, &event.Magnitude, &event.Latitude, &event.Longitude, &event.Depth, &event.Tsunami, &eventTime,
+		&event.Alert, &event.MMI, &event.CDI, &event.Felt, &event.Significance, &event.Status, &event.MagType, &event.Network, &event.URL, &event.MainshockId, &event.CountryCode)
+	if err != nil {
+		return nil, err
+	}
+	event.Time = eventTime.UnixMilli()
+	return event, nil
+}
+
+// GetEventsBetween returns stored canonical events whose origin time falls in [from, to].
+func GetEventsBetween(from, to time.Time) ([]*model.Event, error) {
+	events := []*model.Event{}
+	query := `select ` + eventColumns + ` from events where event_time between $1 and $2 order by event_time`
+	rows, err := DB.Query(query, from, to)
+	if err != nil {
+		log.Println("error fetching events from db: ", err.Error())
+		return events, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			log.Println("error populating the value into variables: ", err.Error())
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// SaveEvent stores the event under its canonical id and records every id the
+// event is known by as an alias of it. A stored event is refreshed when the
+// same source reports a newer update of it; the magnitude is left to
+// UpdateEventMagnitude so revisions are still noticed.
+func SaveEvent(canonicalId string, event *model.Event) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `insert into events (id, source, title, place, magnitude, latitude, longitude, depth, tsunami, event_time,
+			alert, mmi, cdi, felt, sig, status, mag_type, net, url, updated)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+			nullif($11::text, ''), nullif($12::double precision, 0), nullif($13::double precision, 0), nullif($14::integer, 0), nullif($15::integer, 0),
+			nullif($16::text, ''), nullif($17::text, ''), nullif($18::text, ''), nullif($19::text, ''), $20)
+		on conflict (id) do update set
+			title = excluded.title, place = excluded.place, latitude = excluded.latitude, longitude = excluded.longitude,
+			depth = excluded.depth, tsunami = excluded.tsunami, alert = excluded.alert, mmi = excluded.mmi, cdi = excluded.cdi,
+			felt = excluded.felt, sig = excluded.sig, status = excluded.status, mag_type = excluded.mag_type, url = excluded.url,
+			updated = excluded.updated
+		where events.source = excluded.source and excluded.updated > coalesce(events.updated, '-infinity')`
+	var updated *time.Time
+	if event.Updated > 0 {
+		t := time.UnixMilli(event.Updated).UTC()
+		updated = &t
+	}
+	_, err = tx.Exec(query, canonicalId, event.Source, event.Title, event.Place, event.Magnitude, event.Latitude, event.Longitude, event.Depth, event.Tsunami, time.UnixMilli(event.Time).UTC(),
+		event.Alert, event.MMI, event.CDI, event.Felt, event.Significance, event.Status, event.MagType, event.Network, event.URL, updated)
+	if err != nil {
+		log.Println("error inserting the event", err.Error())
+		return err
+	}
+
+	aliasQuery := `insert into event_alias (alias_id, event_id) values ($1, $2) on conflict (alias_id) do nothing`
+	for _, id := range append([]string{canonicalId}, event.Ids...) {
+		if _, err = tx.Exec(aliasQuery, id, canonicalId); err != nil {
+			log.Println("error inserting the event alias", err.Error())
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetEvent returns the stored canonical event, or nil if there is none.
+func GetEvent(id string) (*model.Event, error) {
+	query := `select ` + eventColumns + ` from events where id = $1`
+	event, err := scanEvent(DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return event, err
+}
+
+// SetEventCountry records the country of the epicenter so stored events can
+// be looked up by country.
+func SetEventCountry(id, countryCode string) error {
+	query := `update events set country_code = nullif($1, '') where id = $2`
+	_, err := DB.Exec(query, countryCode, id)
+	if err != nil {
+		log.Println("error updating event country", err.Error())
+	}
+	return err
+}
+
+// GetEventHistory returns stored events since from, newest first, in
+// countryCode if it is set and with place containing place if that is set.
+func GetEventHistory(countryCode, place string, from time.Time, limit, offset int) ([]*model.Event, error) {
+	events := []*model.Event{}
+	query := `select ` + eventColumns + ` from events
+		where event_time >= $1 and ($2 = '' or country_code = $2) and ($3 = '' or place ilike '%' || $3 || '%')
+		order by event_time desc limit $4 offset $5`
+	rows, err := DB.Query(query, from, countryCode, likeEscaper.Replace(place), limit, offset)
+	if err != nil {
+		log.Println("error fetching event history from db: ", err.Error())
+		return events, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			log.Println("error populating the value into variables: ", err.Error())
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// likeEscaper makes user input match literally in a like pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func UpdateEventMagnitude(id string, magnitude float64) error {
+	query := `update events set magnitude = $1 where id = $2`
+	_, err := DB.Exec(query, magnitude, id)
+	if err != nil {
+		log.Println("error updating event magnitude", err.Error())
+	}
+	return err
+}
+
+func ClearOldEvents(retentionDays int) error {
+	query := `DELETE FROM events WHERE event_time < NOW() - make_interval(days => $1)`
+	_, err := DB.Exec(query, retentionDays)
+	if err != nil {
+		log.Println("Error clearing old events", err)
+	}
+	return err
+}
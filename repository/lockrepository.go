@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+)
+
+// TryAdvisoryLock takes a session level advisory lock on conn without waiting.
+// The lock lives as long as the session, and conn.Close hands the session
+// back to the pool still holding it, so a conn whose lock wasn't released
+// must be closed with DiscardConn instead.
+func TryAdvisoryLock(ctx context.Context, conn *sql.Conn, key int64) (bool, error) {
+	var acquired bool
+	err := conn.QueryRowContext(ctx, `select pg_try_advisory_lock($1)`, key).Scan(&acquired)
+	if err != nil {
+		log.Println("Error taking advisory lock:", err)
+	}
+	return acquired, err
+}
+
+func AdvisoryUnlock(ctx context.Context, conn *sql.Conn, key int64) error {
+	_, err := conn.ExecContext(ctx, `select pg_advisory_unlock($1)`, key)
+	if err != nil {
+		log.Println("Error releasing advisory lock:", err)
+	}
+	return err
+}
+
+// DiscardConn closes the connection itself rather than returning it to the
+// pool, ending the session and any advisory lock it holds.
+func DiscardConn(conn *sql.Conn) {
+	// Raw drops a connection whose callback reports it bad.
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"log"
+)
+
+func AddUserRegion(chatId int64, region string) error {
+	query := `insert into user_regions (chat_id, region) values ($1, $2) on conflict (chat_id, region) do nothing`
+	_, err := DB.Exec(query, chatId, region)
+	if err != nil {
+		log.Println("error adding user region", err.Error())
+	}
+	return err
+}
+
+func RemoveUserRegion(chatId int64, region string) error {
+	query := `delete from user_regions where chat_id = $1 and region = $2`
+	_, err := DB.Exec(query, chatId, region)
+	if err != nil {
+		log.Println("error removing user region", err.Error())
+	}
+	return err
+}
+
+func ClearUserRegions(chatId int64) error {
+	query := `delete from user_regions where chat_id = $1`
+	_, err := DB.Exec(query, chatId)
+	if err != nil {
+		log.Println("error clearing user regions", err.Error())
+	}
+	return err
+}
+
+// GetUserRegions returns the region references (predefined ids or custom
+// boxes) the user is subscribed to.
+func GetUserRegions(chatId int64) ([]string, error) {
+	regions := []string{}
+	query := `select region from user_regions where chat_id = $1 order by region`
+	rows, err := DB.Query(query, chatId)
+	if err != nil {
+		log.Println("error fetching user regions", err.Error())
+		return regions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var region string
+		if err = rows.Scan(&region); err != nil {
+			log.Println("error populating the value into variables: ", err.Error())
+			continue
+		}
+		regions = append(regions, region)
+	}
+	return regions, rows.Err()
+}
@@ -0,0 +1,96 @@
+package repository
+
+import "log"
+
+// schema is applied on every start, so each statement has to be idempotent.
+var schema = []string{
+	`create table if not exists telegramuser (
+		id bigint primary key,
+		username text,
+		country text,
+		keyboardsent boolean not null default false
+	)`,
+	`create table if not exists sent_alerts (
+		earthquake_id text not null,
+		chat_id bigint not null,
+		inserted_at timestamptz not null default now(),
+		unique (earthquake_id, chat_id)
+	)`,
+	`alter table telegramuser add column if not exists min_magnitude double precision not null default 0`,
+	`alter table telegramuser add column if not exists language text`,
+	`alter table telegramuser add column if not exists language_code text`,
+	`alter table telegramuser add column if not exists send_location boolean not null default false`,
+	`alter table telegramuser add column if not exists watch_latitude double precision`,
+	`alter table telegramuser add column if not exists watch_longitude double precision`,
+	`alter table telegramuser add column if not exists min_alert text`,
+	`alter table telegramuser add column if not exists reviewed_only boolean not null default false`,
+	`alter table telegramuser add column if not exists max_depth double precision`,
+	`alter table telegramuser add column if not exists min_intensity double precision`,
+	`alter table telegramuser add column if not exists last_alert_title text`,
+	`alter table telegramuser add column if not exists last_alert_at timestamptz`,
+	`create table if not exists user_regions (
+		chat_id bigint not null,
+		region text not null,
+		primary key (chat_id, region)
+	)`,
+	`create table if not exists events (
+		id text primary key,
+		source text not null,
+		title text,
+		place text,
+		magnitude double precision,
+		latitude double precision not null,
+		longitude double precision not null,
+		depth double precision,
+		tsunami integer not null default 0,
+		event_time timestamptz not null,
+		inserted_at timestamptz not null default now()
+	)`,
+	`create index if not exists events_event_time_idx on events (event_time)`,
+	`alter table events
+		add column if not exists alert text,
+		add column if not exists mmi double precision,
+		add column if not exists cdi double precision,
+		add column if not exists felt integer,
+		add column if not exists sig integer,
+		add column if not exists status text,
+		add column if not exists mag_type text,
+		add column if not exists net text,
+		add column if not exists url text`,
+	`alter table events add column if not exists mainshock_id text`,
+	`alter table events add column if not exists country_code text`,
+	`alter table events add column if not exists updated timestamptz`,
+	`create index if not exists events_country_code_idx on events (country_code, event_time)`,
+	`alter table sent_alerts add column if not exists message_id bigint`,
+	`create table if not exists sequence_summaries (
+		chat_id bigint not null,
+		sequence_id text not null,
+		event_id text not null,
+		queued_at timestamptz not null default now(),
+		primary key (chat_id, event_id)
+	)`,
+	`create table if not exists swarms (
+		id text primary key,
+		latitude double precision not null,
+		longitude double precision not null,
+		event_count integer not null,
+		min_magnitude double precision not null,
+		max_magnitude double precision not null,
+		started_at timestamptz not null,
+		ended_at timestamptz not null
+	)`,
+	`create table if not exists event_alias (
+		alias_id text primary key,
+		event_id text not null references events (id) on delete cascade
+	)`,
+}
+
+func Migrate() error {
+	for _, statement := range schema {
+		if _, err := DB.Exec(statement); err != nil {
+			log.Println("error applying schema", err.Error())
+			return err
+		}
+	}
+	return nil
+}
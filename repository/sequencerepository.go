@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"alerts/model"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetMainshockCandidates returns stored events of at least minMagnitude whose
+// origin time falls in [from, to], largest first.
+func GetMainshockCandidates(minMagnitude float64, from, to time.Time) ([]*model.Event, error) {
+	events := []*model.Event{}
+	query := `select ` + eventColumns + ` from events where magnitude >= $1 and event_time between $2 and $3 order by magnitude desc`
+	rows, err := DB.Query(query, minMagnitude, from, to)
+	if err != nil {
+		log.Println("error fetching mainshock candidates from db: ", err.Error())
+		return events, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			log.Println("error populating the value into variables: ", err.Error())
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func SetMainshock(id, mainshockId string) error {
+	query := `update events set mainshock_id = $1 where id = $2`
+	_, err := DB.Exec(query, mainshockId, id)
+	if err != nil {
+		log.Println("error updating the mainshock", err.Error())
+	}
+	return err
+}
+
+// SetAlertMessageId records the Telegram message an alert went out as, so
+// later messages about the same quake can reply to it.
+func SetAlertMessageId(quakeId string, chatId, messageId int64) error {
+	query := `update sent_alerts set message_id = $1 where earthquake_id = $2 and chat_id = $3`
+	_, err := DB.Exec(query, messageId, quakeId, chatId)
+	if err != nil {
+		log.Println("error updating the alert message id", err.Error())
+	}
+	return err
+}
+
+// GetAlertMessageId returns the message the alert for quakeId went out as in
+// chatId, or 0 if none was recorded.
+func GetAlertMessageId(quakeId string, chatId int64) (int64, error) {
+	var messageId sql.NullInt64
+	query := `select message_id from sent_alerts where earthquake_id = $1 and chat_id = $2`
+	err := DB.QueryRow(query, quakeId, chatId).Scan(&messageId)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return messageId.Int64, err
+}
+
+// QueueSummaryEvent holds an aftershock back for the next summary of its
+// sequence in chatId.
+func QueueSummaryEvent(chatId int64, sequenceId, eventId string) error {
+	query := `insert into sequence_summaries (chat_id, sequence_id, event_id) values ($1, $2, $3) on conflict (chat_id, event_id) do nothing`
+	_, err := DB.Exec(query, chatId, sequenceId, eventId)
+	if err != nil {
+		log.Println("error queueing the aftershock", err.Error())
+	}
+	return err
+}
+
+// GetDueSummaries returns the queued aftershocks of every sequence and chat
+// whose oldest queued aftershock was queued at or before due.
+func GetDueSummaries(due time.Time) ([]*model.SequenceSummary, error) {
+	summaries := []*model.SequenceSummary{}
+	query := `select chat_id, sequence_id, array_agg(event_id order by queued_at) from sequence_summaries
+		group by chat_id, sequence_id having min(queued_at) <= $1`
+	rows, err := DB.Query(query, due)
+	if err != nil {
+		log.Println("error fetching due summaries from db: ", err.Error())
+		return summaries, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		summary := new(model.SequenceSummary)
+		if err := rows.Scan(&summary.ChatId, &summary.SequenceId, pq.Array(&summary.EventIds)); err != nil {
+			log.Println("error populating the value into variables: ", err.Error())
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+func DeleteSummaryEvents(chatId int64, sequenceId string, eventIds []string) error {
+	query := `delete from sequence_summaries where chat_id = $1 and sequence_id = $2 and event_id = any($3)`
+	_, err := DB.Exec(query, chatId, sequenceId, pq.Array(eventIds))
+	if err != nil {
+		log.Println("error deleting summarised aftershocks", err.Error())
+	}
+	return err
+}
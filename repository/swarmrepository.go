@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"alerts/model"
+	"log"
+	"time"
+)
+
+// GetSwarmsSince returns the swarms whose last quake occurred at or after from.
+func GetSwarmsSince(from time.Time) ([]*model.Swarm, error) {
+	swarms := []*model.Swarm{}
+	query := `select id, latitude, longitude, event_count, min_magnitude, max_magnitude, started_at, ended_at
+		from swarms where ended_at >= $1`
+	rows, err := DB.Query(query, from)
+	if err != nil {
+		log.Println("error fetching swarms from db: ", err.Error())
+		return swarms, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var start, end time.Time
+		swarm := new(model.Swarm)
+		err := rows.Scan(&swarm.Id, &swarm.Latitude, &swarm.Longitude, &swarm.Count, &swarm.MinMagnitude, &swarm.MaxMagnitude, &start, &end)
+		if err != nil {
+			log.Println("error populating the value into variables: ", err.Error())
+			continue
+		}
+		swarm.Start = start.UnixMilli()
+		swarm.End = end.UnixMilli()
+		swarms = append(swarms, swarm)
+	}
+	return swarms, rows.Err()
+}
+
+// SaveSwarm stores a new swarm or updates the figures of a known one.
+func SaveSwarm(swarm *model.Swarm) error {
+	query := `insert into swarms (id, latitude, longitude, event_count, min_magnitude, max_magnitude, started_at, ended_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)
+		on conflict (id) do update set event_count = excluded.event_count, min_magnitude = excluded.min_magnitude,
+			max_magnitude = excluded.max_magnitude, started_at = excluded.started_at, ended_at = excluded.ended_at`
+	_, err := DB.Exec(query, swarm.Id, swarm.Latitude, swarm.Longitude, swarm.Count, swarm.MinMagnitude, swarm.MaxMagnitude,
+		time.UnixMilli(swarm.Start).UTC(), time.UnixMilli(swarm.End).UTC())
+	if err != nil {
+		log.Println("error saving the swarm", err.Error())
+	}
+	return err
+}
+
+func ClearOldSwarms(retentionDays int) error {
+	query := `DELETE FROM swarms WHERE ended_at < NOW() - make_interval(days => $1)`
+	_, err := DB.Exec(query, retentionDays)
+	if err != nil {
+		log.Println("Error clearing old swarms", err)
+	}
+	return err
+}